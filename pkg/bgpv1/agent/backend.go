@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"fmt"
+
+	"github.com/cilium/cilium/pkg/hive/cell"
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+)
+
+// BGPRouterManagerFactory constructs a BGPRouterManager implementation for a
+// single named backend (e.g. "gobgp", "frr", "bird"). Factories are supplied
+// to the hive by providing a BackendFactoryOut, which places them in the
+// "bgp-router-manager-factories" group collected into
+// ControllerParams.RouterMgrFactories.
+type BGPRouterManagerFactory struct {
+	// Name is the backend name matched against DaemonConfig.BGPBackend.
+	Name string
+	// New constructs the BGPRouterManager for this backend.
+	New func() (BGPRouterManager, error)
+	// Capabilities reports the CiliumBGPPeeringPolicy features this backend
+	// implements, used by Reconcile to reject unsupported policies instead
+	// of silently dropping configuration.
+	Capabilities BackendCapabilities
+}
+
+// BackendCapabilities enumerates the optional CiliumBGPPeeringPolicy
+// features a BGPRouterManager backend is able to honor. A zero value means
+// "supports nothing beyond basic peering", which is always required.
+type BackendCapabilities struct {
+	// GracefulRestart indicates support for RFC 4724 graceful restart.
+	GracefulRestart bool
+	// MultiPathRelax indicates support for eBGP multipath relax-as-check.
+	MultiPathRelax bool
+	// LeaderElection indicates the backend can be safely reconfigured from
+	// multiple nodes racing for the same lease (FullWithdrawal on standby).
+	LeaderElection bool
+}
+
+// ErrUnsupportedFeature is returned by Reconcile when the selected
+// CiliumBGPPeeringPolicy requires a feature the active backend cannot
+// honor, rather than silently ignoring the field.
+type ErrUnsupportedFeature struct {
+	Backend string
+	Feature string
+}
+
+func (e *ErrUnsupportedFeature) Error() string {
+	return fmt.Sprintf("BGP backend %q does not support %q, cannot apply CiliumBGPPeeringPolicy", e.Backend, e.Feature)
+}
+
+// backendRegistry resolves a backend name to its BGPRouterManagerFactory.
+// It is built once from the set of factories registered with the hive and
+// consulted by NewController to pick the backend named by
+// DaemonConfig.BGPBackend.
+type backendRegistry struct {
+	factories map[string]BGPRouterManagerFactory
+}
+
+func newBackendRegistry(factories []BGPRouterManagerFactory) *backendRegistry {
+	r := &backendRegistry{factories: make(map[string]BGPRouterManagerFactory, len(factories))}
+	for _, f := range factories {
+		r.factories[f.Name] = f
+	}
+	return r
+}
+
+// Build constructs the BGPRouterManager for the named backend.
+func (r *backendRegistry) Build(name string) (BGPRouterManager, BackendCapabilities, error) {
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, BackendCapabilities{}, fmt.Errorf("unknown BGP backend %q, known backends: %v", name, r.names())
+	}
+	mgr, err := factory.New()
+	if err != nil {
+		return nil, BackendCapabilities{}, fmt.Errorf("failed to construct BGP backend %q: %w", name, err)
+	}
+	return mgr, factory.Capabilities, nil
+}
+
+func (r *backendRegistry) names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	return names
+}
+
+// BackendFactoryOut is the hive output type backend packages (gobgp, frr,
+// bird, ...) provide to register themselves with the Controller.
+type BackendFactoryOut struct {
+	cell.Out
+
+	Factory BGPRouterManagerFactory `group:"bgp-router-manager-factories"`
+}
+
+// requireCapability returns an *ErrUnsupportedFeature if the active
+// backend's capabilities don't satisfy what policy requires for feature.
+func requireCapability(backendName string, have bool, feature string) error {
+	if have {
+		return nil
+	}
+	return &ErrUnsupportedFeature{Backend: backendName, Feature: feature}
+}
+
+// requireVirtualRouterCapabilities checks every virtual router in policy
+// against the active backend's capabilities, rejecting the policy with an
+// *ErrUnsupportedFeature for the first requested feature (gracefulRestart,
+// multiPathRelax) the backend cannot honor, rather than silently dropping
+// the field.
+func requireVirtualRouterCapabilities(policy *v2alpha1api.CiliumBGPPeeringPolicy, backendName string, caps BackendCapabilities) error {
+	for _, vr := range policy.Spec.VirtualRouters {
+		if vr.GracefulRestart != nil && *vr.GracefulRestart {
+			if err := requireCapability(backendName, caps.GracefulRestart, "gracefulRestart"); err != nil {
+				return err
+			}
+		}
+		if vr.MultiPathRelax != nil && *vr.MultiPathRelax {
+			if err := requireCapability(backendName, caps.MultiPathRelax, "multiPathRelax"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
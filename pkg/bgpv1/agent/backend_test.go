@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+)
+
+func TestRequireCapability(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, requireCapability("frr", true, "gracefulRestart"))
+
+	err := requireCapability("bird", false, "gracefulRestart")
+	require.Error(t, err)
+	var unsupported *ErrUnsupportedFeature
+	require.ErrorAs(t, err, &unsupported)
+	require.Equal(t, "bird", unsupported.Backend)
+	require.Equal(t, "gracefulRestart", unsupported.Feature)
+}
+
+func TestRequireVirtualRouterCapabilities(t *testing.T) {
+	t.Parallel()
+
+	caps := BackendCapabilities{GracefulRestart: true, MultiPathRelax: false}
+
+	t.Run("feature not requested", func(t *testing.T) {
+		t.Parallel()
+		policy := policyWithVR("p", 10, v2alpha1api.BGPMergeStrategyExclusive, v2alpha1api.CiliumBGPVirtualRouter{
+			LocalASN: 65000,
+		})
+		require.NoError(t, requireVirtualRouterCapabilities(policy, "bird", caps))
+	})
+
+	t.Run("requested feature supported", func(t *testing.T) {
+		t.Parallel()
+		policy := policyWithVR("p", 10, v2alpha1api.BGPMergeStrategyExclusive, v2alpha1api.CiliumBGPVirtualRouter{
+			LocalASN:        65000,
+			GracefulRestart: boolPtr(true),
+		})
+		require.NoError(t, requireVirtualRouterCapabilities(policy, "frr", caps))
+	})
+
+	t.Run("requested feature unsupported", func(t *testing.T) {
+		t.Parallel()
+		policy := policyWithVR("p", 10, v2alpha1api.BGPMergeStrategyExclusive, v2alpha1api.CiliumBGPVirtualRouter{
+			LocalASN:       65000,
+			MultiPathRelax: boolPtr(true),
+		})
+		err := requireVirtualRouterCapabilities(policy, "frr", caps)
+		require.Error(t, err)
+		var unsupported *ErrUnsupportedFeature
+		require.ErrorAs(t, err, &unsupported)
+		require.Equal(t, "multiPathRelax", unsupported.Feature)
+	})
+}
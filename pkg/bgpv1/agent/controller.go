@@ -9,7 +9,12 @@ import (
 	"net"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	noopTrace "go.opentelemetry.io/otel/trace/noop"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sLabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
@@ -19,6 +24,8 @@ import (
 
 	"github.com/cilium/cilium/pkg/hive"
 	"github.com/cilium/cilium/pkg/hive/cell"
+	bgpmetrics "github.com/cilium/cilium/pkg/hubble/metrics/bgp"
+	"github.com/cilium/cilium/pkg/hubble/metrics/util"
 	ipamOption "github.com/cilium/cilium/pkg/ipam/option"
 	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
 	"github.com/cilium/cilium/pkg/k8s/client"
@@ -122,6 +129,32 @@ type Controller struct {
 	// and provides a declarative API for configuring BGP peers.
 	BGPMgr BGPRouterManager
 
+	// BGPMetrics, when non-nil, receives every PeerEvent published by
+	// BGPMgr.Subscribe so peer session health is observable the same way
+	// dropped flows are. It is optional so callers that don't wire up
+	// Hubble metrics incur no overhead.
+	BGPMetrics *bgpmetrics.Handler
+
+	// LeaderElector gates ConfigurePeers on holding the lease described by
+	// the current policy's "leaderElection" stanza. It is nil when no
+	// applicable policy requests leader election, in which case the node
+	// always behaves as leader.
+	LeaderElector *leaderElector
+	// leaderElectorCancel stops the currently running leaderElector's Run
+	// loop independently of the rest of the workerpool, so the Controller
+	// can tear it down when a later reconciliation selects a policy that no
+	// longer requests leader election.
+	leaderElectorCancel context.CancelFunc
+
+	clientset client.Clientset
+	tracer    trace.Tracer
+
+	// backendName and backendCapabilities describe the active BGPMgr
+	// implementation, used by Reconcile to reject policies requiring a
+	// feature the backend cannot honor via ErrUnsupportedFeature.
+	backendName         string
+	backendCapabilities BackendCapabilities
+
 	workerpool *workerpool.WorkerPool
 }
 
@@ -171,20 +204,43 @@ func configureForClusterPoolIPAM(factory externalversions.SharedInformerFactory,
 type ControllerParams struct {
 	cell.In
 
-	Lifecycle    hive.Lifecycle
-	Clientset    client.Clientset
-	RouterMgr    BGPRouterManager
-	DaemonConfig *option.DaemonConfig
+	Lifecycle hive.Lifecycle
+	Clientset client.Clientset
+	// RouterMgr is used directly when DaemonConfig.BGPBackend is unset,
+	// preserving single-backend wiring for callers (and tests) that
+	// construct a BGPRouterManager themselves rather than going through
+	// RouterMgrFactories.
+	RouterMgr BGPRouterManager
+	// RouterMgrFactories are collected from every backend package
+	// (pkg/bgpv1/gobgp, pkg/bgpv1/frr, pkg/bgpv1/bird, ...) that registers a
+	// BackendFactoryOut with the hive. NewController selects among them by
+	// name using DaemonConfig.BGPBackend.
+	RouterMgrFactories []BGPRouterManagerFactory `group:"bgp-router-manager-factories"`
+	DaemonConfig       *option.DaemonConfig
+	// TracerProvider is used to create spans around each Signaler-triggered
+	// reconciliation. It defaults to a no-op provider when tracing is
+	// disabled, see TracingCell.
+	TracerProvider trace.TracerProvider
+	// MetricsRegistry is the Hubble metrics registry BGPMetrics registers
+	// its series with. It is optional: callers that don't wire up a
+	// registry (e.g. tests) simply get no BGP peer telemetry.
+	MetricsRegistry *prometheus.Registry `optional:"true"`
 }
 
+// bgpMetricsTTL is how long an idle peer's Hubble metrics series is retained
+// before eviction, matching the default used by other Hubble metrics
+// handlers registered through this package.
+const bgpMetricsTTL = 10 * time.Minute
+
 // NewController constructs a new BGP Control Plane Controller.
 //
 // When the constructor returns the Controller will be actively watching for
 // events and configuring BGP related sub-systems.
 //
-// The constructor requires an implementation of BGPRouterManager to be provided.
-// This implementation defines which BGP backend will be used (GoBGP, FRR, Bird, etc...)
-// NOTE: only GoBGP currently implemented.
+// The constructor requires an implementation of BGPRouterManager to be provided,
+// either directly via ControllerParams.RouterMgr or, when
+// ControllerParams.DaemonConfig.BGPBackend names one, selected from
+// ControllerParams.RouterMgrFactories (GoBGP, FRR, Bird, ...).
 func NewController(params ControllerParams) (*Controller, error) {
 	// If the BGP control plane is disabled, just return nil. This way the hive dependency graph is always static
 	// regardless of config. The lifecycle has not been appended so no work will be done.
@@ -199,9 +255,34 @@ func NewController(params ControllerParams) (*Controller, error) {
 		factory = externalversions.NewSharedInformerFactory(params.Clientset, 0)
 	)
 
+	tp := params.TracerProvider
+	if tp == nil {
+		tp = noopTrace.NewTracerProvider()
+	}
+
 	c := Controller{
-		Sig:    sig,
-		BGPMgr: params.RouterMgr,
+		Sig:       sig,
+		BGPMgr:    params.RouterMgr,
+		clientset: params.Clientset,
+		tracer:    tp.Tracer("github.com/cilium/cilium/pkg/bgpv1/agent"),
+	}
+
+	if params.MetricsRegistry != nil {
+		c.BGPMetrics = bgpmetrics.NewHandler(params.MetricsRegistry, bgpMetricsTTL)
+	}
+
+	// a configured DaemonConfig.BGPBackend selects among the registered
+	// backend factories; otherwise fall back to the single BGPRouterManager
+	// provided directly, preserving pre-plugin-system wiring.
+	if backend := params.DaemonConfig.BGPBackend; backend != "" {
+		registry := newBackendRegistry(params.RouterMgrFactories)
+		mgr, caps, err := registry.Build(backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select BGP backend: %w", err)
+		}
+		c.BGPMgr = mgr
+		c.backendName = backend
+		c.backendCapabilities = caps
 	}
 
 	// setup is dictate by the type of IPAM being used. If Kubernetes IPAM is
@@ -251,7 +332,7 @@ func NewController(params ControllerParams) (*Controller, error) {
 
 // Start is called by hive after all of our dependencies have been started.
 func (c *Controller) Start(_ hive.HookContext) error {
-	c.workerpool = workerpool.New(3)
+	c.workerpool = workerpool.New(4)
 	c.workerpool.Submit("policy-informer", func(ctx context.Context) error {
 		c.policyInformer.Run(ctx.Done())
 		return nil
@@ -262,6 +343,13 @@ func (c *Controller) Start(_ hive.HookContext) error {
 		return nil
 	})
 
+	if c.BGPMetrics != nil {
+		c.workerpool.Submit("bgp-peer-telemetry", func(ctx context.Context) error {
+			c.consumePeerEvents(ctx)
+			return nil
+		})
+	}
+
 	c.workerpool.Submit("controller", func(ctx context.Context) error {
 		c.Run(ctx)
 		return nil
@@ -273,6 +361,10 @@ func (c *Controller) Start(_ hive.HookContext) error {
 // Stop is called by hive upon shutdown, after all of our dependants have been stopped.
 // We should perform a graceful shutdown and return as soon as done or when the stop context is done.
 func (c *Controller) Stop(ctx hive.HookContext) error {
+	if c.leaderElectorCancel != nil {
+		c.leaderElectorCancel()
+	}
+
 	doneChan := make(chan struct{})
 	go func() {
 		c.workerpool.Close()
@@ -335,11 +427,18 @@ func (c *Controller) Run(ctx context.Context) {
 //
 // Policy selection follows the following rules:
 //   - A policy matches a node if said policy's "nodeSelector" field matches
-//     the node's labels
-//   - If (N > 1) policies match the provided *corev1.Node an error is returned.
-//     only a single policy may apply to a node to avoid ambiguity at this stage
-//     of development.
+//     the node's labels.
+//   - If more than one matching policy sets "mergeStrategy" to "Exclusive"
+//     (the default), the highest "priority" policy wins; a tie between two
+//     Exclusive policies is ambiguous and returns ErrMultiplePolicies.
+//   - Matching policies with a "Union" or "Override" mergeStrategy are instead
+//     synthesized into a single policy by mergePolicies, unioning their
+//     virtual routers on a per-localASN basis. An Exclusive policy always
+//     takes precedence over merge candidates.
 func PolicySelection(ctx context.Context, labels map[string]string, policies []*v2alpha1api.CiliumBGPPeeringPolicy) (*v2alpha1api.CiliumBGPPeeringPolicy, error) {
+	_, span := trace.SpanFromContext(ctx).TracerProvider().Tracer("github.com/cilium/cilium/pkg/bgpv1/agent").Start(ctx, "PolicySelection")
+	defer span.End()
+
 	var (
 		l = log.WithFields(logrus.Fields{
 			"component": "PolicySelection",
@@ -347,15 +446,10 @@ func PolicySelection(ctx context.Context, labels map[string]string, policies []*
 	)
 	// determine which policies match our node's labels.
 	var (
-		selected   *v2alpha1api.CiliumBGPPeeringPolicy
+		matching   []*v2alpha1api.CiliumBGPPeeringPolicy
 		slimLabels = slimlabels.Set(labels)
 	)
 
-	// range over policies and see if any match this node's labels.
-	//
-	// for now, only a single BGP policy can be applied to a node. if more then
-	// one policy applies to a node, we disconnect from all BGP peers and log
-	// an error.
 	for _, policy := range policies {
 		nodeSelector, err := slimmetav1.LabelSelectorAsSelector(policy.Spec.NodeSelector)
 		if err != nil {
@@ -366,16 +460,30 @@ func PolicySelection(ctx context.Context, labels map[string]string, policies []*
 			"nodeLabels":         slimLabels,
 		}).Debug("Comparing BGP policy node selector with node's labels")
 		if nodeSelector.Matches(slimLabels) {
-			if selected != nil {
-				return nil, ErrMultiplePolicies
-			}
-			selected = policy
+			matching = append(matching, policy)
 		}
 	}
 
-	// no policy was discovered, tell router manager to withdrawal peers if they
-	// are configured.
-	return selected, nil
+	if len(matching) == 0 {
+		// no policy was discovered, tell router manager to withdrawal peers if
+		// they are configured.
+		return nil, nil
+	}
+
+	exclusive, mergeable, err := mergeCandidates(matching)
+	if err != nil {
+		return nil, err
+	}
+	if exclusive != nil {
+		return exclusive, nil
+	}
+
+	merged, err := mergePolicies(mergeable)
+	if err != nil {
+		l.WithError(err).Error("Failed to merge matching CiliumBGPPeeringPolicies")
+		return nil, err
+	}
+	return merged, nil
 }
 
 // Reconcile is the control loop for the Controller.
@@ -395,9 +503,14 @@ func (c *Controller) Reconcile(ctx context.Context) error {
 		})
 	)
 
+	ctx, span := c.tracer.Start(ctx, "Controller.Reconcile")
+	defer span.End()
+
 	// retrieve all CiliumBGPPeeringPolicies
 	policies, err := c.PolicyLister.List(k8sLabels.NewSelector())
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to list CiliumBGPPeeringPolicies")
 		return fmt.Errorf("failed to list CiliumBGPPeeringPolicies")
 	}
 	l.WithField("count", len(policies)).Debug("Successfully listed CiliumBGPPeeringPolicies")
@@ -405,14 +518,21 @@ func (c *Controller) Reconcile(ctx context.Context) error {
 	// perform policy selection based on node.
 	labels, err := c.NodeSpec.Labels()
 	if err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to retrieve labels for Node: %w", err)
 	}
 	policy, err := PolicySelection(ctx, labels, policies)
 	if err != nil {
 		l.WithError(err).Error("Policy selection failed")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "policy selection failed")
 		c.FullWithdrawal(ctx)
 		return err
 	}
+	if policy != nil {
+		span.SetAttributes(attribute.String("bgp.policy.name", policy.Name))
+	}
+	span.SetAttributes(attribute.String("bgp.node.name", nodetypes.GetName()))
 	if policy == nil {
 		// no policy was discovered, tell router manager to withdrawal peers if
 		// they are configured.
@@ -421,6 +541,31 @@ func (c *Controller) Reconcile(ctx context.Context) error {
 		return nil
 	}
 
+	if policy.Spec.LeaderElection != nil && c.backendName != "" {
+		if err := requireCapability(c.backendName, c.backendCapabilities.LeaderElection, "leaderElection"); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "backend missing required capability")
+			return err
+		}
+	}
+
+	if c.backendName != "" {
+		if err := requireVirtualRouterCapabilities(policy, c.backendName, c.backendCapabilities); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "backend missing required capability")
+			return err
+		}
+	}
+
+	if err := c.ensureLeaderElector(policy, labels); err != nil {
+		return fmt.Errorf("failed to configure BGP leader election: %w", err)
+	}
+	if c.LeaderElector != nil && !c.LeaderElector.IsLeader() {
+		l.Debug("Not the elected BGP leader for this policy, withdrawing any existing BGP sessions")
+		c.FullWithdrawal(ctx)
+		return nil
+	}
+
 	// parse any virtual router specific attributes defined on this node via
 	// kubernetes annotations
 	//
@@ -449,9 +594,17 @@ func (c *Controller) Reconcile(ctx context.Context) error {
 		IPv6:        nodeaddr.GetIPv6(),
 	}
 
+	span.SetAttributes(
+		attribute.Int("bgp.podcidrs.count", len(state.PodCIDRs)),
+		attribute.String("bgp.ipv4", state.IPv4.String()),
+		attribute.String("bgp.ipv6", state.IPv6.String()),
+	)
+
 	// call bgp sub-systems required to apply this policy's BGP topology.
 	l.Debug("Asking configured BGPRouterManager to configure peering")
 	if err := c.BGPMgr.ConfigurePeers(ctx, policy, state); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ConfigurePeers failed")
 		return fmt.Errorf("failed to configure BGP peers, cannot apply BGP peering policy: %w", err)
 	}
 
@@ -463,3 +616,127 @@ func (c *Controller) Reconcile(ctx context.Context) error {
 func (c *Controller) FullWithdrawal(ctx context.Context) {
 	_ = c.BGPMgr.ConfigurePeers(ctx, nil, nil) // cannot fail, no need for error handling
 }
+
+// LeaderElectionStatus reports this node's current standing in BGP leader
+// election, for status surfaces (e.g. `cilium-dbg bgp peers`) that want it
+// without scraping the BGPLeaderGauge Prometheus metric.
+type LeaderElectionStatus struct {
+	// Enabled is false when the currently selected policy doesn't request
+	// leader election at all.
+	Enabled bool
+	// Leading is true when this node currently holds the lease. It is
+	// always false when Enabled is false, and also false for a node excluded
+	// from candidacy by the policy's leaderElection.nodeSelector.
+	Leading bool
+}
+
+// LeaderElectionStatus returns c's current leader election standing.
+func (c *Controller) LeaderElectionStatus() LeaderElectionStatus {
+	if c.LeaderElector == nil {
+		return LeaderElectionStatus{}
+	}
+	return LeaderElectionStatus{Enabled: true, Leading: c.LeaderElector.IsLeader()}
+}
+
+// peerEventSubscriber is implemented by BGPRouterManager backends that
+// publish PeerEvents. It is checked via type assertion rather than added to
+// BGPRouterManager itself, so backends that don't yet support peer event
+// telemetry remain valid BGPRouterManager implementations.
+type peerEventSubscriber interface {
+	Subscribe() <-chan PeerEvent
+}
+
+// consumePeerEvents forwards every PeerEvent published on c.BGPMgr's
+// Subscribe channel to c.BGPMetrics until ctx is cancelled, giving BGP
+// session health the same always-on observability dropped flows already get
+// from dropHandler. It is a no-op if the active backend doesn't implement
+// peerEventSubscriber.
+func (c *Controller) consumePeerEvents(ctx context.Context) {
+	subscriber, ok := c.BGPMgr.(peerEventSubscriber)
+	if !ok {
+		return
+	}
+
+	events := subscriber.Subscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.BGPMetrics.ProcessEvent(event)
+		}
+	}
+}
+
+// ensureLeaderElector lazily starts a leaderElector for policy's
+// "leaderElection" stanza the first time such a policy is selected, and
+// tears it down again if a later reconciliation selects a policy which no
+// longer requests leader election. It is a no-op while a real elector is
+// running, whichever policy started it: a change to the selected policy's
+// "leaderElection" stanza does not restart the elector with the new lease.
+//
+// If the stanza's NodeSelector excludes this node from candidacy, no
+// elector is started at all and c.LeaderElector is set to a elector that
+// never acquires the lease, so Reconcile's IsLeader check withdraws this
+// node's BGP sessions exactly as it would for a lost race.
+func (c *Controller) ensureLeaderElector(policy *v2alpha1api.CiliumBGPPeeringPolicy, labels map[string]string) error {
+	if policy.Spec.LeaderElection == nil {
+		if c.leaderElectorCancel != nil {
+			c.leaderElectorCancel()
+			c.leaderElectorCancel = nil
+		}
+		c.LeaderElector = nil
+		return nil
+	}
+
+	candidate, err := nodeIsLeaderCandidate(policy.Spec.LeaderElection.NodeSelector, labels)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate leaderElection nodeSelector: %w", err)
+	}
+	if !candidate {
+		if c.leaderElectorCancel != nil {
+			c.leaderElectorCancel()
+			c.leaderElectorCancel = nil
+		}
+		c.LeaderElector = &leaderElector{}
+		return nil
+	}
+
+	if c.LeaderElector != nil && c.leaderElectorCancel != nil {
+		// already running a real elector; Controller only supports one
+		// active policy at a time so there is nothing further to reconcile
+		// here.
+		return nil
+	}
+
+	spec := leaderElectionSpec{
+		LeaseName:      policy.Spec.LeaderElection.LeaseName,
+		LeaseNamespace: policy.Spec.LeaderElection.LeaseNamespace,
+		LeaseDuration:  policy.Spec.LeaderElection.LeaseDuration,
+		RenewDeadline:  policy.Spec.LeaderElection.RenewDeadline,
+		RetryPeriod:    policy.Spec.LeaderElection.RetryPeriod,
+		NodeSelector:   policy.Spec.LeaderElection.NodeSelector,
+	}
+
+	le, err := newLeaderElector(c.clientset, spec, func(context.Context) {
+		util.BGPLeaderGauge.WithLabelValues(policy.Name).Set(1)
+	}, func() {
+		util.BGPLeaderGauge.WithLabelValues(policy.Name).Set(0)
+		c.FullWithdrawal(context.Background())
+	})
+	if err != nil {
+		return err
+	}
+
+	leCtx, cancel := context.WithCancel(context.Background())
+	c.LeaderElector = le
+	c.leaderElectorCancel = cancel
+
+	return c.workerpool.Submit(fmt.Sprintf("leader-elector-%s", policy.Name), func(context.Context) error {
+		le.Run(leCtx)
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/atomic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/cilium/cilium/pkg/k8s/client"
+	nodetypes "github.com/cilium/cilium/pkg/node/types"
+)
+
+// leaderElector wraps a k8s.io/client-go leaderelection.LeaderElector so a
+// Controller can gate BGP speaking on holding a coordination.v1 Lease,
+// mirroring the pattern used by the L2 announcer for active/standby
+// failover.
+//
+// leaderElector is safe to read concurrently via IsLeader once started.
+type leaderElector struct {
+	elector *leaderelection.LeaderElector
+
+	// leading is flipped by the OnStartedLeading/OnStoppedLeading callbacks
+	// and read by Controller.Reconcile to decide whether BGPMgr.ConfigurePeers
+	// should be called.
+	leading atomic.Bool
+
+	onStart func(ctx context.Context)
+	onStop  func()
+}
+
+// newLeaderElector constructs a leaderElector for the given
+// CiliumBGPLeaderElection stanza. onStart is invoked once this node acquires
+// the lease, onStop once it is lost or the elector is shut down; Reconcile
+// uses these to drive FullWithdrawal.
+func newLeaderElector(clientset client.Clientset, spec leaderElectionSpec, onStart func(ctx context.Context), onStop func()) (*leaderElector, error) {
+	identity := nodetypes.GetName()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      spec.LeaseName,
+			Namespace: spec.LeaseNamespace,
+		},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	le := &leaderElector{onStart: onStart, onStop: onStop}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: spec.LeaseDuration,
+		RenewDeadline: spec.RenewDeadline,
+		RetryPeriod:   spec.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				le.leading.Store(true)
+				log.WithFields(logrus.Fields{
+					"component": "leaderElector",
+					"lease":     spec.LeaseName,
+				}).Info("Acquired BGP leader election lease")
+				if le.onStart != nil {
+					le.onStart(ctx)
+				}
+			},
+			OnStoppedLeading: func() {
+				le.leading.Store(false)
+				log.WithFields(logrus.Fields{
+					"component": "leaderElector",
+					"lease":     spec.LeaseName,
+				}).Info("Lost BGP leader election lease, withdrawing BGP sessions")
+				if le.onStop != nil {
+					le.onStop()
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	le.elector = elector
+
+	return le, nil
+}
+
+// Run blocks running the leader election loop until ctx is cancelled.
+func (l *leaderElector) Run(ctx context.Context) {
+	l.elector.Run(ctx)
+}
+
+// IsLeader reports whether this node currently holds the lease. Callers that
+// don't use leader election at all (leaderElector == nil) should treat the
+// node as always leading.
+func (l *leaderElector) IsLeader() bool {
+	if l == nil {
+		return true
+	}
+	return l.leading.Load()
+}
+
+// leaderElectionSpec is the parsed form of a CiliumBGPPeeringPolicy's
+// "leaderElection" stanza.
+type leaderElectionSpec struct {
+	LeaseName      string
+	LeaseNamespace string
+	LeaseDuration  time.Duration
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+	NodeSelector   *metav1.LabelSelector
+}
+
+// nodeIsLeaderCandidate reports whether nodeLabels satisfy selector. A nil
+// selector matches every node, preserving today's behavior for policies that
+// set "leaderElection" without narrowing which nodes may hold the lease.
+func nodeIsLeaderCandidate(selector *metav1.LabelSelector, nodeLabels map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return sel.Matches(k8sLabels.Set(nodeLabels)), nil
+}
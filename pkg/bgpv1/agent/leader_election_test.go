@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeIsLeaderCandidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		labels   map[string]string
+		want     bool
+	}{
+		{
+			name:     "nil selector matches every node",
+			selector: nil,
+			labels:   map[string]string{"role": "worker"},
+			want:     true,
+		},
+		{
+			name:     "matching selector",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"bgp-candidate": "true"}},
+			labels:   map[string]string{"bgp-candidate": "true"},
+			want:     true,
+		},
+		{
+			name:     "non-matching selector",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"bgp-candidate": "true"}},
+			labels:   map[string]string{"bgp-candidate": "false"},
+			want:     false,
+		},
+		{
+			name:     "selector key absent from node labels",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"bgp-candidate": "true"}},
+			labels:   map[string]string{},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nodeIsLeaderCandidate(tt.selector, tt.labels)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
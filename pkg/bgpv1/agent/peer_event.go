@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+// SessionState mirrors the BGP finite state machine states (RFC 4271 §8)
+// that are interesting to report to operators; intermediate states are
+// collapsed into "Connecting" since Cilium's consumers only care about
+// Idle/Connecting/Established.
+type SessionState string
+
+const (
+	SessionIdle        SessionState = "idle"
+	SessionConnecting  SessionState = "connecting"
+	SessionEstablished SessionState = "established"
+)
+
+// PeerEvent is emitted by a BGPRouterManager's Subscribe channel whenever a
+// peer's session state transitions or it advertises/receives route updates.
+// Controller.Start consumes these under its own workerpool goroutine and
+// forwards them to the Hubble "bgp" metrics handler.
+type PeerEvent struct {
+	// LocalASN is the ASN of the virtual router the peer belongs to.
+	LocalASN int64
+	// PeerASN is the remote peer's advertised ASN.
+	PeerASN int64
+	// PeerAddress is the neighbor address of the peer this event concerns.
+	PeerAddress string
+	// VRF is the virtual routing and forwarding instance's name the peer's
+	// virtual router is scoped to, or the empty string for the default VRF.
+	VRF string
+
+	// State is set when this event represents a session state transition.
+	State SessionState
+	// Flapped is true when State transitioned away from and back to
+	// SessionEstablished, i.e. a session flap rather than the first
+	// establishment.
+	Flapped bool
+
+	// RoutesAdvertised and RoutesReceived are set when this event represents
+	// a route count update for the peer rather than a state transition.
+	RoutesAdvertised int
+	RoutesReceived   int
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"fmt"
+	"sort"
+
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+)
+
+// ErrConflictingNeighbors is a structured error returned by mergePolicies
+// when two or more merge candidate policies configure the same neighbor
+// address with different remote ASNs, making it impossible to synthesize a
+// single peering configuration for that neighbor.
+type ErrConflictingNeighbors struct {
+	// PeerAddress is the neighbor address shared by the conflicting policies.
+	PeerAddress string
+	// Policies lists the names of the CiliumBGPPeeringPolicies in conflict.
+	Policies []string
+}
+
+func (e *ErrConflictingNeighbors) Error() string {
+	return fmt.Sprintf(
+		"CiliumBGPPeeringPolicies %v configure neighbor %q with different peerASN values, cannot merge",
+		e.Policies, e.PeerAddress,
+	)
+}
+
+// mergeCandidates partitions the provided policies into the single highest
+// priority Exclusive policy (if any apply) and the remaining policies which
+// request Union or Override semantics and are therefore eligible to be
+// synthesized into a single merged policy.
+//
+// Ties in priority between two Exclusive policies are ambiguous and reported
+// via ErrMultiplePolicies, preserving today's behavior for that case.
+func mergeCandidates(policies []*v2alpha1api.CiliumBGPPeeringPolicy) (exclusive *v2alpha1api.CiliumBGPPeeringPolicy, mergeable []*v2alpha1api.CiliumBGPPeeringPolicy, err error) {
+	sorted := make([]*v2alpha1api.CiliumBGPPeeringPolicy, len(policies))
+	copy(sorted, policies)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Spec.Priority > sorted[j].Spec.Priority
+	})
+
+	for _, policy := range sorted {
+		switch policy.Spec.MergeStrategy {
+		case v2alpha1api.BGPMergeStrategyExclusive, "":
+			if exclusive != nil {
+				if exclusive.Spec.Priority == policy.Spec.Priority {
+					return nil, nil, ErrMultiplePolicies
+				}
+				// lower priority Exclusive policies are simply shadowed.
+				continue
+			}
+			exclusive = policy
+		default:
+			mergeable = append(mergeable, policy)
+		}
+	}
+
+	// an Exclusive policy always wins over any merge candidates, mirroring
+	// the precedence a cluster operator would expect from "priority".
+	if exclusive != nil {
+		return exclusive, nil, nil
+	}
+
+	return nil, mergeable, nil
+}
+
+// mergePolicies synthesizes a single CiliumBGPPeeringPolicy from the provided
+// Union/Override policies by unioning their virtual routers on a per
+// localASN basis. Neighbors are deduplicated by peer address; a higher
+// priority Override policy's virtual router replaces a lower priority one
+// sharing the same localASN outright, while Union policies are deep-merged
+// field by field.
+//
+// Two policies that disagree on the remote ASN for the same neighbor address
+// result in an *ErrConflictingNeighbors, since Cilium cannot establish two
+// different BGP sessions under one local address/peer address tuple.
+func mergePolicies(policies []*v2alpha1api.CiliumBGPPeeringPolicy) (*v2alpha1api.CiliumBGPPeeringPolicy, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	if len(policies) == 1 {
+		return policies[0], nil
+	}
+
+	// policies are already sorted highest priority first by mergeCandidates.
+	merged := &v2alpha1api.CiliumBGPPeeringPolicy{
+		ObjectMeta: policies[0].ObjectMeta,
+		Spec:       v2alpha1api.CiliumBGPPeeringPolicySpec{},
+	}
+
+	byASN := map[int64]*v2alpha1api.CiliumBGPVirtualRouter{}
+	// byASNStrategy records the MergeStrategy of the policy that first
+	// established each localASN's entry in byASN, so a later, lower priority
+	// Union policy can be refused if the established entry came from an
+	// Override policy - Override's "replace outright" semantics must hold
+	// regardless of which side of the merge is being evaluated.
+	byASNStrategy := map[int64]v2alpha1api.BGPMergeStrategy{}
+	// neighborOwner tracks, for every peer address seen so far, which
+	// policy first contributed it and under which ASN, so conflicts can be
+	// reported with both offending policy names.
+	neighborOwner := map[string]struct {
+		policy  string
+		peerASN int64
+	}{}
+
+	for _, policy := range policies {
+		for i := range policy.Spec.VirtualRouters {
+			vr := policy.Spec.VirtualRouters[i]
+
+			existing, ok := byASN[vr.LocalASN]
+			if !ok {
+				cp := vr
+				byASN[vr.LocalASN] = &cp
+				byASNStrategy[vr.LocalASN] = policy.Spec.MergeStrategy
+				for _, n := range vr.Neighbors {
+					neighborOwner[n.PeerAddress] = struct {
+						policy  string
+						peerASN int64
+					}{policy.Name, n.PeerASN}
+				}
+				continue
+			}
+
+			if policy.Spec.MergeStrategy == v2alpha1api.BGPMergeStrategyOverride || byASNStrategy[vr.LocalASN] == v2alpha1api.BGPMergeStrategyOverride {
+				// either this policy or the highest priority policy already
+				// established for this localASN is Override, which always
+				// replaces outright rather than merging - and since policies
+				// are processed in priority order, that established entry
+				// already won.
+				continue
+			}
+
+			merged, err := mergeVirtualRouters(existing, &vr, neighborOwner, policy.Name)
+			if err != nil {
+				return nil, err
+			}
+			byASN[vr.LocalASN] = merged
+		}
+	}
+
+	asns := make([]int64, 0, len(byASN))
+	for asn := range byASN {
+		asns = append(asns, asn)
+	}
+	sort.Slice(asns, func(i, j int) bool { return asns[i] < asns[j] })
+
+	for _, asn := range asns {
+		merged.Spec.VirtualRouters = append(merged.Spec.VirtualRouters, *byASN[asn])
+	}
+
+	return merged, nil
+}
+
+// mergeVirtualRouters unions b's neighbors and service/pod advertisements
+// into a, deduplicating peers by PeerAddress. ownerPolicy is the name of the
+// policy contributing b, used to enrich conflict errors.
+func mergeVirtualRouters(a, b *v2alpha1api.CiliumBGPVirtualRouter, neighborOwner map[string]struct {
+	policy  string
+	peerASN int64
+}, ownerPolicy string) (*v2alpha1api.CiliumBGPVirtualRouter, error) {
+	cp := *a
+	cp.Neighbors = append([]v2alpha1api.CiliumBGPNeighbor{}, a.Neighbors...)
+
+	for _, n := range b.Neighbors {
+		owner, seen := neighborOwner[n.PeerAddress]
+		if !seen {
+			neighborOwner[n.PeerAddress] = struct {
+				policy  string
+				peerASN int64
+			}{ownerPolicy, n.PeerASN}
+			cp.Neighbors = append(cp.Neighbors, n)
+			continue
+		}
+		if owner.peerASN != n.PeerASN {
+			return nil, &ErrConflictingNeighbors{
+				PeerAddress: n.PeerAddress,
+				Policies:    []string{owner.policy, ownerPolicy},
+			}
+		}
+		// same neighbor, same peerASN: already represented, nothing to do.
+	}
+
+	if cp.ExportPodCIDR == nil {
+		cp.ExportPodCIDR = b.ExportPodCIDR
+	}
+	if cp.ServiceSelector == nil {
+		cp.ServiceSelector = b.ServiceSelector
+	}
+
+	return &cp, nil
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func policyWithVR(name string, priority int, strategy v2alpha1api.BGPMergeStrategy, vrs ...v2alpha1api.CiliumBGPVirtualRouter) *v2alpha1api.CiliumBGPPeeringPolicy {
+	return &v2alpha1api.CiliumBGPPeeringPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v2alpha1api.CiliumBGPPeeringPolicySpec{
+			Priority:       priority,
+			MergeStrategy:  strategy,
+			VirtualRouters: vrs,
+		},
+	}
+}
+
+func TestMergeCandidatesExclusiveWins(t *testing.T) {
+	t.Parallel()
+
+	low := policyWithVR("low", 10, v2alpha1api.BGPMergeStrategyExclusive)
+	high := policyWithVR("high", 20, v2alpha1api.BGPMergeStrategyExclusive)
+	union := policyWithVR("union", 30, v2alpha1api.BGPMergeStrategyUnion)
+
+	exclusive, mergeable, err := mergeCandidates([]*v2alpha1api.CiliumBGPPeeringPolicy{low, high, union})
+	require.NoError(t, err)
+	require.Same(t, high, exclusive)
+	require.Nil(t, mergeable)
+}
+
+func TestMergeCandidatesTiedExclusivePriority(t *testing.T) {
+	t.Parallel()
+
+	a := policyWithVR("a", 10, v2alpha1api.BGPMergeStrategyExclusive)
+	b := policyWithVR("b", 10, v2alpha1api.BGPMergeStrategyExclusive)
+
+	_, _, err := mergeCandidates([]*v2alpha1api.CiliumBGPPeeringPolicy{a, b})
+	require.ErrorIs(t, err, ErrMultiplePolicies)
+}
+
+func TestMergeCandidatesNoExclusiveReturnsMergeable(t *testing.T) {
+	t.Parallel()
+
+	a := policyWithVR("a", 10, v2alpha1api.BGPMergeStrategyUnion)
+	b := policyWithVR("b", 20, v2alpha1api.BGPMergeStrategyOverride)
+
+	exclusive, mergeable, err := mergeCandidates([]*v2alpha1api.CiliumBGPPeeringPolicy{a, b})
+	require.NoError(t, err)
+	require.Nil(t, exclusive)
+	require.Equal(t, []*v2alpha1api.CiliumBGPPeeringPolicy{b, a}, mergeable)
+}
+
+func TestMergePoliciesExportPodCIDRHighestPriorityWins(t *testing.T) {
+	t.Parallel()
+
+	// high is processed first (highest priority) and leaves ExportPodCIDR
+	// unset; low is a lower priority Union policy that does set it. The
+	// merged result must still take low's value, since nothing higher
+	// priority claimed the field first.
+	high := policyWithVR("high", 20, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN: 65000,
+	})
+	low := policyWithVR("low", 10, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN:      65000,
+		ExportPodCIDR: boolPtr(true),
+	})
+
+	merged, err := mergePolicies([]*v2alpha1api.CiliumBGPPeeringPolicy{high, low})
+	require.NoError(t, err)
+	require.Len(t, merged.Spec.VirtualRouters, 1)
+	require.NotNil(t, merged.Spec.VirtualRouters[0].ExportPodCIDR)
+	require.True(t, *merged.Spec.VirtualRouters[0].ExportPodCIDR)
+
+	// now flip it: the highest priority policy claims ExportPodCIDR=false,
+	// and a lower priority policy disagrees with true - highest priority
+	// must win.
+	high2 := policyWithVR("high", 20, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN:      65000,
+		ExportPodCIDR: boolPtr(false),
+	})
+	low2 := policyWithVR("low", 10, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN:      65000,
+		ExportPodCIDR: boolPtr(true),
+	})
+
+	merged2, err := mergePolicies([]*v2alpha1api.CiliumBGPPeeringPolicy{high2, low2})
+	require.NoError(t, err)
+	require.False(t, *merged2.Spec.VirtualRouters[0].ExportPodCIDR)
+}
+
+func TestMergePoliciesOverrideEntryRejectsLaterUnion(t *testing.T) {
+	t.Parallel()
+
+	override := policyWithVR("override", 20, v2alpha1api.BGPMergeStrategyOverride, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN: 65000,
+		Neighbors: []v2alpha1api.CiliumBGPNeighbor{
+			{PeerAddress: "10.0.0.1/32", PeerASN: 65001},
+		},
+	})
+	union := policyWithVR("union", 10, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN: 65000,
+		Neighbors: []v2alpha1api.CiliumBGPNeighbor{
+			{PeerAddress: "10.0.0.2/32", PeerASN: 65002},
+		},
+	})
+
+	merged, err := mergePolicies([]*v2alpha1api.CiliumBGPPeeringPolicy{override, union})
+	require.NoError(t, err)
+	require.Len(t, merged.Spec.VirtualRouters, 1)
+	// the established Override entry must win outright - union's neighbor
+	// must not have been folded in.
+	require.Len(t, merged.Spec.VirtualRouters[0].Neighbors, 1)
+	require.Equal(t, "10.0.0.1/32", merged.Spec.VirtualRouters[0].Neighbors[0].PeerAddress)
+}
+
+func TestMergePoliciesConflictingNeighbors(t *testing.T) {
+	t.Parallel()
+
+	a := policyWithVR("a", 20, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN: 65000,
+		Neighbors: []v2alpha1api.CiliumBGPNeighbor{
+			{PeerAddress: "10.0.0.1/32", PeerASN: 65001},
+		},
+	})
+	b := policyWithVR("b", 10, v2alpha1api.BGPMergeStrategyUnion, v2alpha1api.CiliumBGPVirtualRouter{
+		LocalASN: 65000,
+		Neighbors: []v2alpha1api.CiliumBGPNeighbor{
+			{PeerAddress: "10.0.0.1/32", PeerASN: 65099},
+		},
+	})
+
+	_, err := mergePolicies([]*v2alpha1api.CiliumBGPPeeringPolicy{a, b})
+	require.Error(t, err)
+
+	var conflict *ErrConflictingNeighbors
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, "10.0.0.1/32", conflict.PeerAddress)
+	require.ElementsMatch(t, []string{"a", "b"}, conflict.Policies)
+}
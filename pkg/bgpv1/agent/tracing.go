@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package agent
+
+import (
+	"context"
+
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	noopTrace "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/cilium/cilium/pkg/hive/cell"
+)
+
+// TracingConfig configures the OpenTelemetry TracerProvider used to trace
+// the BGP control loop.
+type TracingConfig struct {
+	// TracingEndpoint is the OTLP/gRPC collector endpoint spans are exported
+	// to. When unset, a no-op TracerProvider is used and tracing has no
+	// runtime cost beyond span-creation overhead.
+	TracingEndpoint string
+}
+
+// Flags implements cell.Flagger so the tracing endpoint can be set via
+// --tracing-endpoint.
+func (def TracingConfig) Flags(flags *pflag.FlagSet) {
+	flags.String("tracing-endpoint", def.TracingEndpoint, "OTLP/gRPC endpoint BGP control plane traces are exported to, disabled if empty")
+}
+
+// TracerProviderParams are the dependencies needed to construct the
+// TracerProvider cell.
+type TracerProviderParams struct {
+	cell.In
+
+	Lifecycle cell.Lifecycle
+	Config    TracingConfig
+}
+
+// newTracerProvider constructs a trace.TracerProvider for the BGP control
+// plane. If no tracing endpoint is configured a no-op provider is returned
+// so Controller.Reconcile's tracing calls remain cheap no-ops in the common
+// case.
+func newTracerProvider(params TracerProviderParams) (trace.TracerProvider, error) {
+	if params.Config.TracingEndpoint == "" {
+		return noopTrace.NewTracerProvider(), nil
+	}
+
+	exporter, err := otlptrace.New(context.Background(), otlptracegrpc.NewClient(
+		otlptracegrpc.WithEndpoint(params.Config.TracingEndpoint),
+		otlptracegrpc.WithInsecure(),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+
+	params.Lifecycle.Append(cell.Hook{
+		OnStop: func(ctx cell.HookContext) error {
+			return tp.Shutdown(ctx)
+		},
+	})
+
+	return tp, nil
+}
+
+// TracingCell provides the BGP control plane's trace.TracerProvider,
+// defaulting to a no-op provider unless --tracing-endpoint is set.
+var TracingCell = cell.Module(
+	"bgp-tracing",
+	"BGP control plane OpenTelemetry tracing",
+
+	cell.Config(TracingConfig{}),
+	cell.Provide(newTracerProvider),
+)
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package bird implements a pkg/bgpv1/agent.BGPRouterManager backend that
+// drives the BIRD Internet Routing Daemon (https://bird.network.cz) instead
+// of the default GoBGP in-process speaker.
+package bird
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+
+	"github.com/cilium/cilium/pkg/bgpv1/agent"
+	"github.com/cilium/cilium/pkg/hive/cell"
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "bgpv1-bird")
+
+// BackendName is the value of DaemonConfig.BGPBackend that selects this
+// backend.
+const BackendName = "bird"
+
+// Cell registers this package's BGPRouterManagerFactory with the agent's
+// backend registry so DaemonConfig.BGPBackend = "bird" can select it.
+var Cell = cell.Module(
+	"bgpv1-bird",
+	"BIRD BGP control plane backend",
+
+	cell.Provide(func() agent.BackendFactoryOut {
+		return agent.BackendFactoryOut{Factory: Factory()}
+	}),
+)
+
+// Capabilities describes the CiliumBGPPeeringPolicy features this backend
+// is able to honor. BIRD's graceful restart support is per-protocol and not
+// yet wired through, and virtualRouterTemplate doesn't emit a multipath
+// relax-as-check directive either, so both are reported unsupported until
+// that work lands rather than accepting the policy and silently not
+// applying it.
+var Capabilities = agent.BackendCapabilities{
+	GracefulRestart: false,
+	MultiPathRelax:  false,
+	LeaderElection:  true,
+}
+
+// Manager renders bird.conf fragments under ConfDir and reloads the running
+// bird daemon via birdc, rather than managing a BIRD process directly.
+type Manager struct {
+	// ConfDir holds the generated cilium-bgp.conf fragment, expected to be
+	// `include`d from the deployment's main bird.conf.
+	ConfDir string
+	// BirdcSock is the control socket birdc connects to.
+	BirdcSock string
+
+	mu      lock.Mutex
+	applied string
+}
+
+// NewManager constructs a BIRD-backed BGPRouterManager.
+func NewManager(confDir, birdcSock string) (*Manager, error) {
+	if confDir == "" {
+		confDir = "/etc/bird.d"
+	}
+	if birdcSock == "" {
+		birdcSock = "/var/run/bird/bird.ctl"
+	}
+	return &Manager{ConfDir: confDir, BirdcSock: birdcSock}, nil
+}
+
+// Factory returns the BGPRouterManagerFactory registering this backend with
+// the Controller's backend registry.
+func Factory() agent.BGPRouterManagerFactory {
+	return agent.BGPRouterManagerFactory{
+		Name: BackendName,
+		New: func() (agent.BGPRouterManager, error) {
+			return NewManager("", "")
+		},
+		Capabilities: Capabilities,
+	}
+}
+
+// fragmentPath is the path of the generated bird.conf fragment.
+func (m *Manager) fragmentPath() string {
+	return filepath.Join(m.ConfDir, "cilium-bgp.conf")
+}
+
+// ConfigurePeers renders policy into a bird.conf fragment per virtual
+// router and, if it differs from the last applied fragment, writes it and
+// asks the running bird daemon to reload via birdc. A nil policy withdraws
+// all BGP configuration by rendering an empty fragment.
+func (m *Manager) ConfigurePeers(ctx context.Context, policy *v2alpha1api.CiliumBGPPeeringPolicy, state *agent.ControlPlaneState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rendered, err := renderConfig(policy, state)
+	if err != nil {
+		return fmt.Errorf("failed to render bird.conf fragment: %w", err)
+	}
+
+	if rendered == m.applied {
+		return nil
+	}
+
+	if err := os.WriteFile(m.fragmentPath(), []byte(rendered), 0o644); err != nil {
+		return fmt.Errorf("failed to write bird.conf fragment: %w", err)
+	}
+
+	if err := m.reload(ctx); err != nil {
+		return fmt.Errorf("failed to reload bird configuration: %w", err)
+	}
+
+	m.applied = rendered
+	return nil
+}
+
+// reload asks the running bird daemon to re-read its configuration,
+// including our generated fragment, via birdc's "configure" command.
+func (m *Manager) reload(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "birdc", "-s", m.BirdcSock, "configure")
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.WithError(err).WithField("output", string(out)).Error("birdc configure failed")
+		return err
+	}
+	return nil
+}
+
+const virtualRouterTemplate = `protocol bgp cilium_asn{{ .LocalASN }}_{{ .Index }} {
+	local as {{ .LocalASN }};
+{{- range .Neighbors }}
+	neighbor {{ .PeerAddress }} as {{ .PeerASN }};
+{{- end }}
+}
+`
+
+type virtualRouterData struct {
+	v2alpha1api.CiliumBGPVirtualRouter
+	Index int
+}
+
+// renderConfig produces the bird.conf fragment for policy's virtual
+// routers. A nil policy (full withdrawal) renders an empty fragment.
+func renderConfig(policy *v2alpha1api.CiliumBGPPeeringPolicy, state *agent.ControlPlaneState) (string, error) {
+	if policy == nil {
+		return "", nil
+	}
+
+	tmpl, err := template.New("bird.conf").Parse(virtualRouterTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for i, vr := range policy.Spec.VirtualRouters {
+		if err := tmpl.Execute(&buf, virtualRouterData{CiliumBGPVirtualRouter: vr, Index: i}); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package frr implements a pkg/bgpv1/agent.BGPRouterManager backend that
+// drives FRRouting (https://frrouting.org) instead of the default GoBGP
+// in-process speaker, for operators who standardize their fleet on FRR.
+package frr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"text/template"
+
+	"github.com/cilium/cilium/pkg/bgpv1/agent"
+	"github.com/cilium/cilium/pkg/hive/cell"
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "bgpv1-frr")
+
+// BackendName is the value of DaemonConfig.BGPBackend that selects this
+// backend.
+const BackendName = "frr"
+
+// Cell registers this package's BGPRouterManagerFactory with the agent's
+// backend registry so DaemonConfig.BGPBackend = "frr" can select it.
+var Cell = cell.Module(
+	"bgpv1-frr",
+	"FRR BGP control plane backend",
+
+	cell.Provide(func() agent.BackendFactoryOut {
+		return agent.BackendFactoryOut{Factory: Factory()}
+	}),
+)
+
+// Capabilities describes the CiliumBGPPeeringPolicy features this backend
+// is able to honor. virtualRouterTemplate doesn't yet emit a graceful
+// restart directive, so it is reported unsupported until that work lands,
+// rather than accepting the policy and silently not applying it.
+var Capabilities = agent.BackendCapabilities{
+	GracefulRestart: false,
+	MultiPathRelax:  false,
+	LeaderElection:  true,
+}
+
+// Manager drives an FRR instance over vtysh, applying configuration via
+// frr-reload.py against a Unix socket rather than FRR's default VTY TCP
+// port, matching how Cilium already talks to other local daemons.
+type Manager struct {
+	// VtyshSock is the Unix socket frr-reload.py / vtysh connect to.
+	VtyshSock string
+
+	mu      lock.Mutex
+	applied string // last rendered frr.conf fragment, for idempotent no-ops
+}
+
+// NewManager constructs an FRR-backed BGPRouterManager.
+func NewManager(vtyshSock string) (*Manager, error) {
+	if vtyshSock == "" {
+		vtyshSock = "/var/run/frr/frr.sock"
+	}
+	return &Manager{VtyshSock: vtyshSock}, nil
+}
+
+// Factory returns the BGPRouterManagerFactory registering this backend with
+// the Controller's backend registry.
+func Factory() agent.BGPRouterManagerFactory {
+	return agent.BGPRouterManagerFactory{
+		Name: BackendName,
+		New: func() (agent.BGPRouterManager, error) {
+			return NewManager("")
+		},
+		Capabilities: Capabilities,
+	}
+}
+
+// ConfigurePeers renders policy into an frr.conf "router bgp" fragment per
+// virtual router and applies it idempotently via frr-reload.py. A nil
+// policy withdraws all BGP configuration.
+func (m *Manager) ConfigurePeers(ctx context.Context, policy *v2alpha1api.CiliumBGPPeeringPolicy, state *agent.ControlPlaneState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rendered, err := renderConfig(policy, state)
+	if err != nil {
+		return fmt.Errorf("failed to render frr.conf fragment: %w", err)
+	}
+
+	if rendered == m.applied {
+		// idempotent no-op: nothing changed since the last successful apply.
+		return nil
+	}
+
+	if err := m.reload(ctx, rendered); err != nil {
+		return fmt.Errorf("failed to reload FRR configuration: %w", err)
+	}
+
+	m.applied = rendered
+	return nil
+}
+
+// reload pipes rendered to frr-reload.py on stdin and asks it to apply it
+// against m.VtyshSock, the same mechanism FRR's own init scripts use for a
+// graceful, session-preserving reload.
+func (m *Manager) reload(ctx context.Context, rendered string) error {
+	cmd := exec.CommandContext(ctx, "frr-reload.py",
+		"--reload",
+		"--vty_socket", m.VtyshSock,
+		"--stdout",
+		"/dev/stdin",
+	)
+	cmd.Stdin = bytes.NewBufferString(rendered)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.WithError(err).WithField("output", string(out)).Error("frr-reload.py failed")
+		return err
+	}
+	return nil
+}
+
+const virtualRouterTemplate = `router bgp {{ .LocalASN }}
+{{- range .Neighbors }}
+ neighbor {{ .PeerAddress }} remote-as {{ .PeerASN }}
+{{- end }}
+!
+`
+
+// renderConfig produces the frr.conf fragment for policy's virtual routers.
+// A nil policy (full withdrawal) renders an empty fragment.
+func renderConfig(policy *v2alpha1api.CiliumBGPPeeringPolicy, state *agent.ControlPlaneState) (string, error) {
+	if policy == nil {
+		return "", nil
+	}
+
+	tmpl, err := template.New("frr.conf").Parse(virtualRouterTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for _, vr := range policy.Spec.VirtualRouters {
+		for _, n := range vr.Neighbors {
+			if err := validateNeighborAddress(n.PeerAddress); err != nil {
+				return "", err
+			}
+		}
+		if err := tmpl.Execute(&buf, vr); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// validateNeighborAddress is a defensive check exercised before rendering,
+// since a malformed PeerAddress would otherwise only surface once
+// frr-reload.py rejects the generated config. CiliumBGPNeighbor.PeerAddress
+// is always CIDR notation (e.g. "10.0.0.1/32"), matching every other
+// consumer of this field in the tree.
+func validateNeighborAddress(addr string) error {
+	if _, _, err := net.ParseCIDR(addr); err != nil {
+		return fmt.Errorf("invalid neighbor address %q: %w", addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package frr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cilium/cilium/pkg/bgpv1/agent"
+	v2alpha1api "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2alpha1"
+)
+
+func TestValidateNeighborAddress(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validateNeighborAddress("10.0.0.1/32"))
+	require.NoError(t, validateNeighborAddress("2001:db8::1/128"))
+	require.Error(t, validateNeighborAddress("10.0.0.1"))
+	require.Error(t, validateNeighborAddress("not-an-address"))
+}
+
+func TestRenderConfigWithCIDRNeighbor(t *testing.T) {
+	t.Parallel()
+
+	policy := &v2alpha1api.CiliumBGPPeeringPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+		Spec: v2alpha1api.CiliumBGPPeeringPolicySpec{
+			VirtualRouters: []v2alpha1api.CiliumBGPVirtualRouter{
+				{
+					LocalASN: 65000,
+					Neighbors: []v2alpha1api.CiliumBGPNeighbor{
+						{PeerAddress: "10.0.0.1/32", PeerASN: 65001},
+					},
+				},
+			},
+		},
+	}
+
+	rendered, err := renderConfig(policy, &agent.ControlPlaneState{})
+	require.NoError(t, err)
+	require.Contains(t, rendered, "router bgp 65000")
+	require.Contains(t, rendered, "neighbor 10.0.0.1/32 remote-as 65001")
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Hubble
+
+// Package bgp exposes BGP peer session health as Hubble metrics, extending
+// the drop-oriented observability model in pkg/hubble/metrics/drop to BGP
+// control plane events consumed from a bgpv1/agent.BGPRouterManager's
+// Subscribe channel rather than from Hubble's flow pipeline.
+package bgp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cilium/cilium/pkg/bgpv1/agent"
+	"github.com/cilium/cilium/pkg/hubble/metrics/api"
+	"github.com/cilium/cilium/pkg/hubble/metrics/util"
+)
+
+// peerLabels are common to every series this handler emits.
+var peerLabels = []string{"localASN", "peerASN", "peerAddress", "vrf"}
+
+// Handler emits Hubble metrics for BGP peer session health, fed by
+// agent.PeerEvent values rather than Hubble flows.
+type Handler struct {
+	sessionState     *util.CounterVec
+	routesAdvertised *util.GaugeVec
+	routesReceived   *util.GaugeVec
+	sessionFlaps     *util.CounterVec
+}
+
+// NewHandler constructs a Handler and registers its metrics with registry.
+// ttl controls how long an idle peer's series is retained before eviction,
+// matching the TTL semantics of every other Hubble metrics handler.
+func NewHandler(registry *prometheus.Registry, ttl time.Duration) *Handler {
+	h := &Handler{
+		sessionState: util.NewCounterVec(prometheus.CounterOpts{
+			Namespace: api.DefaultPrometheusNamespace,
+			Name:      "bgp_session_state",
+			Help:      "Number of times a BGP peer session entered a given state",
+		}, append(peerLabels, "state"), ttl),
+
+		routesAdvertised: util.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: api.DefaultPrometheusNamespace,
+			Name:      "bgp_routes_advertised",
+			Help:      "Number of routes advertised to a BGP peer, as last reported",
+		}, peerLabels, ttl),
+
+		routesReceived: util.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: api.DefaultPrometheusNamespace,
+			Name:      "bgp_routes_received",
+			Help:      "Number of routes received from a BGP peer, as last reported",
+		}, peerLabels, ttl),
+
+		sessionFlaps: util.NewCounterVec(prometheus.CounterOpts{
+			Namespace: api.DefaultPrometheusNamespace,
+			Name:      "bgp_session_flaps_total",
+			Help:      "Number of times a BGP peer session flapped (transitioned away from and back to Established)",
+		}, peerLabels, ttl),
+	}
+
+	registry.MustRegister(h.sessionState, h.routesAdvertised, h.routesReceived, h.sessionFlaps)
+	return h
+}
+
+// ProcessEvent updates the relevant metric series for a single PeerEvent.
+func (h *Handler) ProcessEvent(event agent.PeerEvent) {
+	labels := []string{
+		strconv.FormatInt(event.LocalASN, 10),
+		strconv.FormatInt(event.PeerASN, 10),
+		event.PeerAddress,
+		event.VRF,
+	}
+
+	if event.State != "" {
+		h.sessionState.WithLabelValues(append(labels, string(event.State))...).Inc()
+		if event.Flapped {
+			h.sessionFlaps.WithLabelValues(labels...).Inc()
+		}
+		// a state transition carries no route counts.
+		return
+	}
+
+	// RoutesAdvertised/RoutesReceived are current-state counts, so a
+	// reported 0 must zero the gauge rather than be treated as a no-op the
+	// way an Add(0) would be.
+	h.routesAdvertised.WithLabelValues(labels...).Set(float64(event.RoutesAdvertised))
+	h.routesReceived.WithLabelValues(labels...).Set(float64(event.RoutesReceived))
+}
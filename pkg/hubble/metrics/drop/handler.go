@@ -8,6 +8,9 @@ import (
 
 	"github.com/cilium/cilium/pkg/hubble/metrics/util"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 
 	flowpb "github.com/cilium/cilium/api/v1/flow"
 	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
@@ -15,6 +18,18 @@ import (
 	monitorAPI "github.com/cilium/cilium/pkg/monitor/api"
 )
 
+// tracer is used to create a span for every dropped flow processed, so that
+// the span can be correlated with the drop_total counter it increments. It
+// is resolved lazily from the otel global TracerProvider, which defaults to
+// a no-op implementation until a TracerProvider is registered by the agent.
+var tracer = otel.Tracer("github.com/cilium/cilium/pkg/hubble/metrics/drop")
+
+// defaultMaxSeries bounds the number of drop_total series kept resident
+// regardless of TTL, protecting the agent's memory under pathological label
+// cardinality (e.g. many short-lived pods each contributing distinct context
+// label values).
+const defaultMaxSeries = 50_000
+
 type dropHandler struct {
 	drops   *util.CounterVec
 	context *api.ContextOptions
@@ -30,11 +45,15 @@ func (d *dropHandler) Init(registry *prometheus.Registry, options api.Options) e
 	contextLabels := d.context.GetLabelNames()
 	labels := append(contextLabels, "reason", "protocol")
 
-	d.drops = util.NewCounterVec(prometheus.CounterOpts{
+	// drop_total is the hottest metric in Hubble's default metric set and can
+	// see unbounded label cardinality (e.g. per-pod context options), so it
+	// gets both an LRU cap and EWMA-aware TTL eviction rather than the plain
+	// TTL every other wrapper defaults to.
+	d.drops = util.NewBoundedCounterVec(prometheus.CounterOpts{
 		Namespace: api.DefaultPrometheusNamespace,
 		Name:      "drop_total",
 		Help:      "Number of drops",
-	}, labels, c.TTL)
+	}, labels, c.TTL, defaultMaxSeries, c.TTL)
 
 	registry.MustRegister(d.drops)
 	return nil
@@ -49,12 +68,25 @@ func (d *dropHandler) ProcessFlow(ctx context.Context, flow *flowpb.Flow) error
 		return nil
 	}
 
+	dropReason := monitorAPI.DropReason(uint8(flow.GetDropReason()))
+
+	// sample a span per dropped flow, tagged with verdict and drop reason so
+	// it can be correlated with the drop_total counter below.
+	ctx, span := tracer.Start(ctx, "dropHandler.ProcessFlow")
+	span.SetAttributes(
+		attribute.String("flow.verdict", flow.GetVerdict().String()),
+		attribute.String("flow.drop_reason", dropReason),
+	)
+	defer span.End()
+
 	contextLabels, err := d.context.GetLabelValues(flow)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to compute context label values")
 		return err
 	}
 
-	labels := append(contextLabels, monitorAPI.DropReason(uint8(flow.GetDropReason())), v1.FlowProtocol(flow))
+	labels := append(contextLabels, dropReason, v1.FlowProtocol(flow))
 
 	d.drops.WithLabelValues(labels...).Inc()
 	return nil
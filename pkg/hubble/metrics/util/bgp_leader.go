@@ -0,0 +1,15 @@
+package util
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// BGPLeaderGauge reports whether this node currently holds the BGP leader
+// election lease for a given CiliumBGPPeeringPolicy, keyed by policy name.
+// It is set to 1 when this node acquires the lease and to 0 when it loses it;
+// a node that has never led a policy reports no series for it. Operators can
+// alert on an unexpected absence of a leader for a policy (no series at
+// value 1).
+var BGPLeaderGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "hubble",
+	Name:      "bgp_leader",
+	Help:      "Whether this node is the elected BGP leader for a policy (1) or standby (0)",
+}, []string{"policy"})
@@ -0,0 +1,278 @@
+package util
+
+import (
+	"container/heap"
+	"container/list"
+	"time"
+)
+
+// EvictionPolicy decides which series a TTL/LRU-wrapped metric vec keeps and
+// which it drops, decoupling CounterVec/HistogramVec/GaugeVec's bookkeeping
+// from any one eviction strategy. Implementations are not safe for
+// concurrent use; callers (seriesCache) serialize access with their own
+// mutex.
+type EvictionPolicy interface {
+	// Name identifies the policy for the cilium_ttl_metric_evictions_total
+	// "policy" label.
+	Name() string
+
+	// Add registers a newly observed key and returns the keys that must be
+	// evicted as a result, e.g. because the policy enforces a hard
+	// cardinality cap.
+	Add(key uint64, now time.Time) (evict []uint64)
+
+	// Touch records that key was accessed again at now.
+	Touch(key uint64, now time.Time)
+
+	// Remove forgets key, e.g. because it was just evicted.
+	Remove(key uint64)
+
+	// Sweep is invoked periodically by a vec's gc goroutine and returns the
+	// keys that should be evicted right now, e.g. because they exceeded a
+	// TTL. Policies with no time-based expiry return nil.
+	Sweep(now time.Time) (evict []uint64)
+}
+
+// ttlRecord tracks the bookkeeping the ttlPolicy needs per key: when it was
+// last touched, and an EWMA of the inter-arrival time between touches.
+type ttlRecord struct {
+	lastSeen     time.Time
+	ewmaInterval time.Duration
+}
+
+// ttlPolicy evicts a key once it has gone untouched for longer than ttl,
+// optionally stretching that window for bursty-but-active series via an EWMA
+// of each series' observed inter-arrival time. With a zero halfLife it is the
+// plain TTL eviction the NewTTL*VecWithReconciliation constructors use.
+type ttlPolicy struct {
+	ttl      time.Duration
+	halfLife time.Duration
+	records  map[uint64]*ttlRecord
+}
+
+func newTTLPolicy(ttl, halfLife time.Duration) *ttlPolicy {
+	return &ttlPolicy{ttl: ttl, halfLife: halfLife, records: make(map[uint64]*ttlRecord)}
+}
+
+func (p *ttlPolicy) Name() string { return "ttl" }
+
+func (p *ttlPolicy) Add(key uint64, now time.Time) []uint64 {
+	p.records[key] = &ttlRecord{lastSeen: now}
+	return nil
+}
+
+func (p *ttlPolicy) Touch(key uint64, now time.Time) {
+	rec, ok := p.records[key]
+	if !ok {
+		p.records[key] = &ttlRecord{lastSeen: now}
+		return
+	}
+	if !rec.lastSeen.IsZero() {
+		rec.ewmaInterval = updateEWMA(rec.ewmaInterval, now.Sub(rec.lastSeen), p.halfLife)
+	}
+	rec.lastSeen = now
+}
+
+func (p *ttlPolicy) Remove(key uint64) {
+	delete(p.records, key)
+}
+
+// effectiveTTL returns the TTL a series must miss before being evicted. When
+// halfLife is configured and the series' observed inter-arrival time exceeds
+// the static ttl, the window is stretched to twice that observed interval so
+// a single missed beat doesn't evict an otherwise bursty series.
+func (p *ttlPolicy) effectiveTTL(rec *ttlRecord) time.Duration {
+	if p.halfLife > 0 && rec.ewmaInterval > p.ttl {
+		return 2 * rec.ewmaInterval
+	}
+	return p.ttl
+}
+
+func (p *ttlPolicy) Sweep(now time.Time) []uint64 {
+	var evict []uint64
+	for key, rec := range p.records {
+		if rec.lastSeen.Add(p.effectiveTTL(rec)).Before(now) {
+			evict = append(evict, key)
+		}
+	}
+	return evict
+}
+
+// lruPolicy enforces a hard cap of maxSeries live keys, evicting the least
+// recently used key once a new one would exceed it. It has no time-based
+// expiry of its own; Sweep always returns nil.
+type lruPolicy struct {
+	maxSeries int
+	ll        *list.List // Values are cache keys (uint64)
+	elems     map[uint64]*list.Element
+}
+
+func newLRUPolicy(maxSeries int) *lruPolicy {
+	return &lruPolicy{maxSeries: maxSeries, ll: list.New(), elems: make(map[uint64]*list.Element)}
+}
+
+func (p *lruPolicy) Name() string { return "lru" }
+
+func (p *lruPolicy) Add(key uint64, _ time.Time) []uint64 {
+	p.elems[key] = p.ll.PushFront(key)
+	if p.maxSeries <= 0 || p.ll.Len() <= p.maxSeries {
+		return nil
+	}
+	back := p.ll.Back()
+	evicted := back.Value.(uint64)
+	p.ll.Remove(back)
+	delete(p.elems, evicted)
+	return []uint64{evicted}
+}
+
+func (p *lruPolicy) Touch(key uint64, _ time.Time) {
+	if elem, ok := p.elems[key]; ok {
+		p.ll.MoveToFront(elem)
+	}
+}
+
+func (p *lruPolicy) Remove(key uint64) {
+	if elem, ok := p.elems[key]; ok {
+		p.ll.Remove(elem)
+		delete(p.elems, key)
+	}
+}
+
+func (p *lruPolicy) Sweep(time.Time) []uint64 { return nil }
+
+// lfuItem is a single entry in an lfuPolicy's min-heap, ordered by hit count
+// so the least frequently used key sits at the heap's root.
+type lfuItem struct {
+	key   uint64
+	count int
+	index int
+}
+
+// lfuHeap is a container/heap min-heap of *lfuItem ordered by ascending
+// count, giving lfuPolicy O(log n) insertion, removal and frequency bumps.
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int           { return len(h) }
+func (h lfuHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h lfuHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i]; h[i].index = i; h[j].index = j }
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// lfuPolicy enforces a hard cap of maxSeries live keys, evicting the least
+// frequently used key (the one touched the fewest times) once a new one
+// would exceed it. It has no time-based expiry of its own; Sweep always
+// returns nil.
+type lfuPolicy struct {
+	maxSeries int
+	items     map[uint64]*lfuItem
+	heap      lfuHeap
+}
+
+func newLFUPolicy(maxSeries int) *lfuPolicy {
+	return &lfuPolicy{maxSeries: maxSeries, items: make(map[uint64]*lfuItem)}
+}
+
+func (p *lfuPolicy) Name() string { return "lfu" }
+
+func (p *lfuPolicy) Add(key uint64, _ time.Time) []uint64 {
+	item := &lfuItem{key: key, count: 1}
+	p.items[key] = item
+	heap.Push(&p.heap, item)
+	if p.maxSeries <= 0 || len(p.items) <= p.maxSeries {
+		return nil
+	}
+	victim := heap.Pop(&p.heap).(*lfuItem)
+	delete(p.items, victim.key)
+	return []uint64{victim.key}
+}
+
+func (p *lfuPolicy) Touch(key uint64, _ time.Time) {
+	item, ok := p.items[key]
+	if !ok {
+		return
+	}
+	item.count++
+	heap.Fix(&p.heap, item.index)
+}
+
+func (p *lfuPolicy) Remove(key uint64) {
+	item, ok := p.items[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&p.heap, item.index)
+	delete(p.items, key)
+}
+
+func (p *lfuPolicy) Sweep(time.Time) []uint64 { return nil }
+
+// hybridPolicy combines ttlPolicy and lruPolicy so a series is evicted once
+// it either goes stale (misses its TTL) or the live set exceeds maxSeries,
+// whichever happens first.
+type hybridPolicy struct {
+	ttl *ttlPolicy
+	lru *lruPolicy
+}
+
+func newHybridPolicy(ttl, halfLife time.Duration, maxSeries int) *hybridPolicy {
+	return &hybridPolicy{ttl: newTTLPolicy(ttl, halfLife), lru: newLRUPolicy(maxSeries)}
+}
+
+func (p *hybridPolicy) Name() string { return "ttl_max_series" }
+
+func (p *hybridPolicy) Add(key uint64, now time.Time) []uint64 {
+	p.ttl.Add(key, now)
+	evicted := p.lru.Add(key, now)
+	for _, key := range evicted {
+		p.ttl.Remove(key)
+	}
+	return evicted
+}
+
+func (p *hybridPolicy) Touch(key uint64, now time.Time) {
+	p.ttl.Touch(key, now)
+	p.lru.Touch(key, now)
+}
+
+func (p *hybridPolicy) Remove(key uint64) {
+	p.ttl.Remove(key)
+	p.lru.Remove(key)
+}
+
+func (p *hybridPolicy) Sweep(now time.Time) []uint64 {
+	evicted := p.ttl.Sweep(now)
+	for _, key := range evicted {
+		p.lru.Remove(key)
+	}
+	return evicted
+}
+
+// newBoundedEvictionPolicy selects ttlPolicy, lruPolicy or hybridPolicy based
+// on which of ttl and maxSeries are configured, preserving the combined
+// TTL+LRU behavior the NewBounded*Vec constructors offered before
+// EvictionPolicy was pluggable. A zero ttl and maxSeries disables eviction
+// entirely (returns nil).
+func newBoundedEvictionPolicy(ttl, halfLife time.Duration, maxSeries int) EvictionPolicy {
+	switch {
+	case maxSeries > 0 && ttl > 0:
+		return newHybridPolicy(ttl, halfLife, maxSeries)
+	case maxSeries > 0:
+		return newLRUPolicy(maxSeries)
+	case ttl > 0:
+		return newTTLPolicy(ttl, halfLife)
+	default:
+		return nil
+	}
+}
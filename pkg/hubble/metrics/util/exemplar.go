@@ -0,0 +1,43 @@
+package util
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxExemplarRunes is the label value length Prometheus' exemplar wire
+// format (OpenMetrics, and the protobuf exposition format) enforces; values
+// beyond this are silently dropped by the client library rather than
+// rejected, which made misconfigured trace/span IDs fail invisibly.
+const maxExemplarRunes = 128
+
+// validateExemplar panics with a clear message if any exemplar label value
+// exceeds maxExemplarRunes, trading a hard but obvious failure for the
+// silent exemplar drop callers would otherwise hit.
+func validateExemplar(exemplar prometheus.Labels) {
+	for name, value := range exemplar {
+		if utf8.RuneCountInString(value) > maxExemplarRunes {
+			panic(fmt.Sprintf("hubble metrics: exemplar label %q value exceeds the %d rune limit Prometheus imposes on exemplars", name, maxExemplarRunes))
+		}
+	}
+}
+
+// AddWithExemplar increments the counter identified by lvs by value and
+// attaches exemplar to that increment, so e.g. a trace ID can be correlated
+// with a spike in a TTL-tracked counter like drop_total. It panics if
+// exemplar violates Prometheus' exemplar label length limit; see
+// validateExemplar.
+func (v *CounterVec) AddWithExemplar(value float64, exemplar prometheus.Labels, lvs ...string) {
+	validateExemplar(exemplar)
+	v.WithLabelValues(lvs...).(prometheus.ExemplarAdder).AddWithExemplar(value, exemplar)
+}
+
+// ObserveWithExemplar records value against the histogram identified by lvs
+// and attaches exemplar to that observation. It panics if exemplar violates
+// Prometheus' exemplar label length limit; see validateExemplar.
+func (v *HistogramVec) ObserveWithExemplar(value float64, exemplar prometheus.Labels, lvs ...string) {
+	validateExemplar(exemplar)
+	v.WithLabelValues(lvs...).(prometheus.ExemplarObserver).ObserveWithExemplar(value, exemplar)
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Gather snapshots the vec's current series under v.cache's mutex, so a scrape can
+// never observe a series the background gc goroutine is in the middle of
+// evicting. Collector.Collect alone doesn't give us this: Prometheus'
+// Registry.Gather locks the registry, not our cache, so a gc eviction
+// racing a scrape could otherwise tear a series' value from its labels.
+// This makes the vec itself satisfy prometheus.Gatherer, which
+// AttachPushSink uses instead of handing the raw collector to the pusher.
+//
+// ConstLabels (set via the prometheus.CounterOpts/HistogramOpts/GaugeOpts
+// passed to the constructors) are applied by the wrapped prometheus vec
+// itself and need no special handling here: they decorate every series
+// without needing to be threaded through WithLabelValues.
+func (v *CounterVec) Gather() ([]*dto.MetricFamily, error) {
+	v.cache.lock()
+	defer v.cache.unlock()
+	return v.gatherReg.Gather()
+}
+
+// Gather is the HistogramVec counterpart of CounterVec.Gather.
+func (v *HistogramVec) Gather() ([]*dto.MetricFamily, error) {
+	v.cache.lock()
+	defer v.cache.unlock()
+	return v.gatherReg.Gather()
+}
+
+// Gather is the GaugeVec counterpart of CounterVec.Gather.
+func (v *GaugeVec) Gather() ([]*dto.MetricFamily, error) {
+	v.cache.lock()
+	defer v.cache.unlock()
+	return v.gatherReg.Gather()
+}
+
+// newGatherRegistry registers collector with a registry dedicated to
+// nothing but that one collector, so Gather can reuse it on every call
+// rather than allocating a throwaway prometheus.Registry per scrape.
+func newGatherRegistry(collector prometheus.Collector) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(collector); err != nil {
+		// collector is this vec's own embedded prometheus vec, registered
+		// into a registry holding nothing else: registration can only fail
+		// for a duplicate or inconsistent descriptor, which is impossible here.
+		panic(fmt.Sprintf("hubble metrics: failed to construct internal gather registry: %v", err))
+	}
+	return reg
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// GaugeVec is a wrapper for prometheus.GaugeVec that keeps track of last
+// entry access and removes data series that an EvictionPolicy decides have
+// gone stale or pushed the cache past a cardinality cap, mirroring
+// CounterVec/HistogramVec.
+type GaugeVec struct {
+	*prometheus.GaugeVec
+	metricName string
+	ttl        time.Duration
+	cache      *seriesCache
+	gcTicker   *time.Ticker
+	gatherReg  *prometheus.Registry
+}
+
+// NewGaugeVec creates a GaugeVec wrapper instance with default gc
+// reconciliation interval of 1 minute.
+func NewGaugeVec(opts prometheus.GaugeOpts, labels []string, ttl time.Duration) *GaugeVec {
+	return NewTTLGaugeVecWithReconciliation(opts, labels, ttl, time.Minute)
+}
+
+// NewTTLGaugeVecWithReconciliation creates a GaugeVec wrapper instance with
+// specified ttl and gc reconciliation interval.
+func NewTTLGaugeVecWithReconciliation(opts prometheus.GaugeOpts, labels []string, ttl time.Duration, reconciliation time.Duration) *GaugeVec {
+	return newGaugeVec(opts, labels, ttl, reconciliation, newBoundedEvictionPolicy(ttl, 0, 0))
+}
+
+// NewBoundedGaugeVec is the GaugeVec counterpart of NewBoundedCounterVec; see
+// its docs for the maxSeries/halfLife semantics.
+func NewBoundedGaugeVec(opts prometheus.GaugeOpts, labels []string, ttl time.Duration, maxSeries int, halfLife time.Duration) *GaugeVec {
+	return newGaugeVec(opts, labels, ttl, time.Minute, newBoundedEvictionPolicy(ttl, halfLife, maxSeries))
+}
+
+// NewLFUGaugeVec is the GaugeVec counterpart of NewLFUCounterVec.
+func NewLFUGaugeVec(opts prometheus.GaugeOpts, labels []string, maxSeries int) *GaugeVec {
+	return newGaugeVec(opts, labels, 0, time.Minute, newLFUPolicy(maxSeries))
+}
+
+// NewGaugeVecWithEvictionPolicy is the GaugeVec counterpart of
+// NewCounterVecWithEvictionPolicy.
+func NewGaugeVecWithEvictionPolicy(opts prometheus.GaugeOpts, labels []string, ttl time.Duration, reconciliation time.Duration, policy EvictionPolicy) *GaugeVec {
+	return newGaugeVec(opts, labels, ttl, reconciliation, policy)
+}
+
+func newGaugeVec(opts prometheus.GaugeOpts, labels []string, ttl time.Duration, reconciliation time.Duration, policy EvictionPolicy) *GaugeVec {
+	var ticker *time.Ticker
+	if ttl > 0 {
+		ticker = time.NewTicker(reconciliation)
+	}
+	v := &GaugeVec{
+		GaugeVec:   prometheus.NewGaugeVec(opts, labels),
+		metricName: prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		ttl:        ttl,
+		gcTicker:   ticker,
+	}
+	v.gatherReg = newGatherRegistry(v.GaugeVec)
+	if policy != nil {
+		v.cache = newSeriesCache(v.metricName, policy, func(lvs []string) { v.DeleteLabelValues(lvs...) })
+	}
+
+	if ticker != nil {
+		go v.gc()
+	}
+	return v
+}
+
+// WithLabelValues updates given labels set access time and returns
+// prometheus.Gauge.
+func (v *GaugeVec) WithLabelValues(lvs ...string) prometheus.Gauge {
+	gauge := v.GaugeVec.WithLabelValues(lvs...)
+	if v.cache != nil {
+		v.cache.touch(lvs)
+	}
+	return gauge
+}
+
+// gc asks the eviction policy which series have expired, every
+// reconciliation interval.
+func (v *GaugeVec) gc() {
+	for range v.gcTicker.C {
+		v.cache.sweep()
+	}
+}
@@ -0,0 +1,23 @@
+// Package openmetrics exposes a Hubble metrics registry over the
+// OpenMetrics text exposition format rather than the classic Prometheus
+// text format, so exemplars and native (sparse) histograms - both of which
+// the classic format silently drops - make it across the wire to a
+// remote-write pipeline.
+package openmetrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler returns an http.Handler serving registry's metrics, negotiating
+// the application/openmetrics-text content type whenever the scraper's
+// Accept header allows it and falling back to the classic text format
+// otherwise.
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
@@ -1,25 +1,72 @@
 package util
 
 import (
+	"math"
 	"time"
 
-	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cespare/xxhash/v2"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-type cacheRecord struct {
-	timestamp time.Time
-	labels    []string
+var (
+	// seriesEvictedTotal counts series dropped by a TTL/LRU wrapper, broken
+	// down by the reason eviction was triggered ("ttl" or "max_series").
+	seriesEvictedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cilium",
+		Subsystem: "hubble_metrics",
+		Name:      "series_evicted_total",
+		Help:      "Number of Hubble metric series evicted by a TTL/LRU wrapper",
+	}, []string{"metric", "reason"})
+
+	// seriesActive reports the number of series currently tracked by a
+	// TTL/LRU wrapper, keyed by the wrapped metric's fully qualified name.
+	seriesActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cilium",
+		Subsystem: "hubble_metrics",
+		Name:      "series_active",
+		Help:      "Number of Hubble metric series currently tracked by a TTL/LRU wrapper",
+	}, []string{"metric"})
+)
+
+func init() {
+	prometheus.MustRegister(seriesEvictedTotal, seriesActive)
+}
+
+// hashLabelValues derives a cache key from a label value slice with
+// xxhash.Sum64, avoiding the string concatenation the naive implementation
+// performed on every WithLabelValues call.
+func hashLabelValues(lvs []string) uint64 {
+	d := xxhash.New()
+	for _, lv := range lvs {
+		_, _ = d.WriteString(lv)
+		_, _ = d.WriteString("\x00")
+	}
+	return d.Sum64()
+}
+
+// updateEWMA folds a newly observed inter-arrival sample into prev with a
+// half-life of halfLife: after halfLife has elapsed the weight of all
+// earlier samples has decayed by half. A non-positive halfLife disables
+// smoothing and returns sample unchanged, preserving the strict TTL
+// semantics callers relied on before EWMA support was added.
+func updateEWMA(prev, sample, halfLife time.Duration) time.Duration {
+	if halfLife <= 0 || prev == 0 {
+		return sample
+	}
+	alpha := 1 - math.Pow(0.5, float64(sample)/float64(halfLife))
+	return time.Duration(alpha*float64(sample) + (1-alpha)*float64(prev))
 }
 
 // CounterVec is a wrapper for prometheus.CounterVec that keeps track of last
-// entry access and removes data series that haven't been used within a specified TTL.
+// entry access and removes data series that an EvictionPolicy decides have
+// gone stale or pushed the cache past a cardinality cap.
 type CounterVec struct {
 	*prometheus.CounterVec
-	ttl      time.Duration
-	cache    map[string]cacheRecord
-	gcTicker *time.Ticker
-	mutex    *lock.Mutex
+	metricName string
+	ttl        time.Duration
+	cache      *seriesCache
+	gcTicker   *time.Ticker
+	gatherReg  *prometheus.Registry
 }
 
 // NewCounterVec creates a CounterVec wrapper instance with default gc
@@ -31,62 +78,85 @@ func NewCounterVec(opts prometheus.CounterOpts, labels []string, ttl time.Durati
 // NewTTLCounterVecWithReconciliation creates a CounterVec wrapper instance with
 // specified ttl and gc reconciliation interval.
 func NewTTLCounterVecWithReconciliation(opts prometheus.CounterOpts, labels []string, ttl time.Duration, reconciliation time.Duration) *CounterVec {
+	return newCounterVec(opts, labels, ttl, reconciliation, newBoundedEvictionPolicy(ttl, 0, 0))
+}
+
+// NewBoundedCounterVec creates a CounterVec wrapper that, in addition to
+// TTL-based expiry, caps the total number of live series at maxSeries
+// (evicting the least recently used series once exceeded) and uses an EWMA
+// of each series' inter-arrival time with the given halfLife so a single
+// missed ttl window doesn't evict an otherwise bursty-but-active series.
+// A maxSeries or halfLife of 0 disables the respective behavior.
+func NewBoundedCounterVec(opts prometheus.CounterOpts, labels []string, ttl time.Duration, maxSeries int, halfLife time.Duration) *CounterVec {
+	return newCounterVec(opts, labels, ttl, time.Minute, newBoundedEvictionPolicy(ttl, halfLife, maxSeries))
+}
+
+// NewLFUCounterVec creates a CounterVec wrapper with no TTL component that
+// caps live series at maxSeries, evicting the least frequently used series
+// once exceeded. Use this instead of NewBoundedCounterVec when a rarely but
+// regularly touched series (e.g. once an hour) should survive indefinitely
+// rather than expire between touches.
+func NewLFUCounterVec(opts prometheus.CounterOpts, labels []string, maxSeries int) *CounterVec {
+	return newCounterVec(opts, labels, 0, time.Minute, newLFUPolicy(maxSeries))
+}
+
+// NewCounterVecWithEvictionPolicy creates a CounterVec wrapper that delegates
+// eviction decisions to policy, for callers that need an EvictionPolicy the
+// constructors above don't assemble directly.
+func NewCounterVecWithEvictionPolicy(opts prometheus.CounterOpts, labels []string, ttl time.Duration, reconciliation time.Duration, policy EvictionPolicy) *CounterVec {
+	return newCounterVec(opts, labels, ttl, reconciliation, policy)
+}
+
+func newCounterVec(opts prometheus.CounterOpts, labels []string, ttl time.Duration, reconciliation time.Duration, policy EvictionPolicy) *CounterVec {
 	var ticker *time.Ticker
 	if ttl > 0 {
 		ticker = time.NewTicker(reconciliation)
 	}
-	counter := &CounterVec{
-		prometheus.NewCounterVec(opts, labels),
-		ttl,
-		make(map[string]cacheRecord),
-		ticker,
-		&lock.Mutex{},
+	v := &CounterVec{
+		CounterVec: prometheus.NewCounterVec(opts, labels),
+		metricName: prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		ttl:        ttl,
+		gcTicker:   ticker,
+	}
+	v.gatherReg = newGatherRegistry(v.CounterVec)
+	if policy != nil {
+		v.cache = newSeriesCache(v.metricName, policy, func(lvs []string) { v.DeleteLabelValues(lvs...) })
 	}
 
-	if nil != ticker {
-		go counter.gc()
+	if ticker != nil {
+		go v.gc()
 	}
-	return counter
+	return v
 }
 
 // WithLabelValues updates given labels set access time and returns
 // prometheus.Counter.
 func (v *CounterVec) WithLabelValues(lvs ...string) prometheus.Counter {
-	if v.ttl > 0 {
-		v.mutex.Lock()
-		defer v.mutex.Unlock()
-		cacheKey := ""
-		for _, l := range lvs {
-			cacheKey += l + "|"
-		}
-		v.cache[cacheKey] = cacheRecord{time.Now(), lvs}
+	counter := v.CounterVec.WithLabelValues(lvs...)
+	if v.cache != nil {
+		v.cache.touch(lvs)
 	}
-	return v.CounterVec.WithLabelValues(lvs...)
+	return counter
 }
 
-// gc removes data series that exceed ttl.
+// gc asks the eviction policy which series have expired, every
+// reconciliation interval.
 func (v *CounterVec) gc() {
-	for _ = range v.gcTicker.C {
-		v.mutex.Lock()
-		for key, cacheRecord := range v.cache {
-			if cacheRecord.timestamp.Add(v.ttl).Before(time.Now()) {
-				v.DeleteLabelValues(cacheRecord.labels...)
-				delete(v.cache, key)
-			}
-		}
-
-		v.mutex.Unlock()
+	for range v.gcTicker.C {
+		v.cache.sweep()
 	}
 }
 
 // HistogramVec is a wrapper for prometheus.HistogramVec that keeps track of last
-// entry access and removes data series that haven't been used within a specified TTL.
+// entry access and removes data series that an EvictionPolicy decides have
+// gone stale or pushed the cache past a cardinality cap.
 type HistogramVec struct {
 	*prometheus.HistogramVec
-	ttl      time.Duration
-	cache    map[string]cacheRecord
-	gcTicker *time.Ticker
-	mutex    lock.Mutex
+	metricName string
+	ttl        time.Duration
+	cache      *seriesCache
+	gcTicker   *time.Ticker
+	gatherReg  *prometheus.Registry
 }
 
 // NewHistogramVec creates a HistogramVec wrapper instance with default gc
@@ -98,51 +168,79 @@ func NewHistogramVec(opts prometheus.HistogramOpts, labels []string, ttl time.Du
 // NewTTLHistogramVecWithReconciliation creates a HistogramVec wrapper instance with
 // specified ttl and gc reconciliation interval.
 func NewTTLHistogramVecWithReconciliation(opts prometheus.HistogramOpts, labels []string, ttl time.Duration, reconciliation time.Duration) *HistogramVec {
+	return newHistogramVec(opts, labels, ttl, reconciliation, newBoundedEvictionPolicy(ttl, 0, 0))
+}
+
+// NewTTLNativeHistogramVecWithReconciliation is the NewTTLHistogramVecWithReconciliation
+// counterpart for native (sparse, exponential-bucket) histograms: opts must
+// set NativeHistogramBucketFactor, and typically NativeHistogramMaxBucketNumber
+// and NativeHistogramMinResetDuration alongside it, which
+// prometheus.NewHistogramVec reads directly to switch the underlying series
+// from fixed buckets to the sparse representation - see
+// prometheus.HistogramOpts for their semantics. Native histograms expose
+// far more resolution per series than a fixed bucket scheme at a fraction of
+// the cardinality, which matters once the series is shipped over
+// remote-write rather than just scraped locally.
+func NewTTLNativeHistogramVecWithReconciliation(opts prometheus.HistogramOpts, labels []string, ttl time.Duration, reconciliation time.Duration) *HistogramVec {
+	if opts.NativeHistogramBucketFactor <= 0 {
+		panic("hubble metrics: NewTTLNativeHistogramVecWithReconciliation requires opts.NativeHistogramBucketFactor > 0; use NewTTLHistogramVecWithReconciliation for classic fixed-bucket histograms")
+	}
+	return newHistogramVec(opts, labels, ttl, reconciliation, newBoundedEvictionPolicy(ttl, 0, 0))
+}
+
+// NewBoundedHistogramVec is the HistogramVec counterpart of
+// NewBoundedCounterVec; see its docs for the maxSeries/halfLife semantics.
+func NewBoundedHistogramVec(opts prometheus.HistogramOpts, labels []string, ttl time.Duration, maxSeries int, halfLife time.Duration) *HistogramVec {
+	return newHistogramVec(opts, labels, ttl, time.Minute, newBoundedEvictionPolicy(ttl, halfLife, maxSeries))
+}
+
+// NewLFUHistogramVec is the HistogramVec counterpart of NewLFUCounterVec.
+func NewLFUHistogramVec(opts prometheus.HistogramOpts, labels []string, maxSeries int) *HistogramVec {
+	return newHistogramVec(opts, labels, 0, time.Minute, newLFUPolicy(maxSeries))
+}
+
+// NewHistogramVecWithEvictionPolicy is the HistogramVec counterpart of
+// NewCounterVecWithEvictionPolicy.
+func NewHistogramVecWithEvictionPolicy(opts prometheus.HistogramOpts, labels []string, ttl time.Duration, reconciliation time.Duration, policy EvictionPolicy) *HistogramVec {
+	return newHistogramVec(opts, labels, ttl, reconciliation, policy)
+}
+
+func newHistogramVec(opts prometheus.HistogramOpts, labels []string, ttl time.Duration, reconciliation time.Duration, policy EvictionPolicy) *HistogramVec {
 	var ticker *time.Ticker
 	if ttl > 0 {
 		ticker = time.NewTicker(reconciliation)
 	}
-	counter := &HistogramVec{
-		prometheus.NewHistogramVec(opts, labels),
-		ttl,
-		make(map[string]cacheRecord),
-		ticker,
-		lock.Mutex{},
+	v := &HistogramVec{
+		HistogramVec: prometheus.NewHistogramVec(opts, labels),
+		metricName:   prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		ttl:          ttl,
+		gcTicker:     ticker,
+	}
+	v.gatherReg = newGatherRegistry(v.HistogramVec)
+	if policy != nil {
+		v.cache = newSeriesCache(v.metricName, policy, func(lvs []string) { v.DeleteLabelValues(lvs...) })
 	}
 
-	if nil != ticker {
-		go counter.gc()
+	if ticker != nil {
+		go v.gc()
 	}
-	return counter
+	return v
 }
 
 // WithLabelValues updates given labels set access time and returns
 // prometheus.Observer.
 func (v *HistogramVec) WithLabelValues(lvs ...string) prometheus.Observer {
-	if v.ttl > 0 {
-		v.mutex.Lock()
-		defer v.mutex.Unlock()
-		cacheKey := ""
-		for _, l := range lvs {
-			cacheKey += l + "|"
-		}
-		v.cache[cacheKey] = cacheRecord{time.Now(), lvs}
+	observer := v.HistogramVec.WithLabelValues(lvs...)
+	if v.cache != nil {
+		v.cache.touch(lvs)
 	}
-	return v.HistogramVec.WithLabelValues(lvs...)
+	return observer
 }
 
-// gc removes data series that exceed ttl.
+// gc asks the eviction policy which series have expired, every
+// reconciliation interval.
 func (v *HistogramVec) gc() {
-	for _ = range v.gcTicker.C {
-		v.mutex.Lock()
-
-		for key, cacheRecord := range v.cache {
-			if cacheRecord.timestamp.Add(v.ttl).Before(time.Now()) {
-				v.DeleteLabelValues(cacheRecord.labels...)
-				delete(v.cache, key)
-			}
-		}
-
-		v.mutex.Unlock()
+	for range v.gcTicker.C {
+		v.cache.sweep()
 	}
 }
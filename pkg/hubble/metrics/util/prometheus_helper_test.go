@@ -1,12 +1,17 @@
 package util
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/cilium/cilium/pkg/lock"
 )
 
 func TestPrometheusCounterVecWithTtl(t *testing.T) {
@@ -41,28 +46,28 @@ func TestPrometheusCounterVecWithTtl(t *testing.T) {
 
 	// then
 	requireDataSeries(t, registry, 2)
-	require.Len(t, sut.cache, 2)
+	require.Equal(t, 2, sut.cache.len())
 
 	// and let time pass
 	time.Sleep(25 * time.Millisecond)
 
 	// then
 	requireDataSeries(t, registry, 1)
-	require.Len(t, sut.cache, 1)
+	require.Equal(t, 1, sut.cache.len())
 
 	// and send metric once
 	sut.WithLabelValues("b0", "b1").Inc()
 
 	// then check there are 2 data series
 	requireDataSeries(t, registry, 2)
-	require.Len(t, sut.cache, 2)
+	require.Equal(t, 2, sut.cache.len())
 
 	// and let time pass
 	time.Sleep(25 * time.Millisecond)
 
 	// then check there is 1 data series
 	requireDataSeries(t, registry, 1)
-	require.Len(t, sut.cache, 1)
+	require.Equal(t, 1, sut.cache.len())
 }
 
 func TestPrometheusCounterVecWithoutTtl(t *testing.T) {
@@ -86,7 +91,7 @@ func TestPrometheusCounterVecWithoutTtl(t *testing.T) {
 
 	// then
 	assert.Equal(t, time.Duration(0), sut.ttl)
-	require.Len(t, sut.cache, 0)
+	require.Equal(t, 0, sut.cache.len())
 
 	requireDataSeries(t, registry, 2)
 
@@ -94,7 +99,7 @@ func TestPrometheusCounterVecWithoutTtl(t *testing.T) {
 	time.Sleep(25 * time.Millisecond)
 
 	// then
-	require.Len(t, sut.cache, 0)
+	require.Equal(t, 0, sut.cache.len())
 	requireDataSeries(t, registry, 2)
 }
 
@@ -130,28 +135,53 @@ func TestPrometheusHistogramVecWithTtl(t *testing.T) {
 
 	// then
 	requireDataSeries(t, registry, 2)
-	require.Len(t, sut.cache, 2)
+	require.Equal(t, 2, sut.cache.len())
 
 	// and let time pass
 	time.Sleep(25 * time.Millisecond)
 
 	// then
 	requireDataSeries(t, registry, 1)
-	require.Len(t, sut.cache, 1)
+	require.Equal(t, 1, sut.cache.len())
 
 	// and send metric once
 	sut.WithLabelValues("b0", "b1").Observe(0.2)
 
 	// then check there are 2 data series
 	requireDataSeries(t, registry, 2)
-	require.Len(t, sut.cache, 2)
+	require.Equal(t, 2, sut.cache.len())
 
 	// and let time pass
 	time.Sleep(25 * time.Millisecond)
 
 	// then check there is 1 data series
 	requireDataSeries(t, registry, 1)
-	require.Len(t, sut.cache, 1)
+	require.Equal(t, 1, sut.cache.len())
+}
+
+func TestNewTTLNativeHistogramVecWithReconciliation(t *testing.T) {
+	t.Parallel()
+
+	labels := []string{"flag", "family"}
+
+	sut := NewTTLNativeHistogramVecWithReconciliation(prometheus.HistogramOpts{
+		Namespace:                      "ns",
+		Name:                           "native_metric_name",
+		NativeHistogramBucketFactor:    1.1,
+		NativeHistogramMaxBucketNumber: 100,
+	}, labels, 10*time.Millisecond, 10*time.Millisecond)
+	defer sut.gcTicker.Stop()
+
+	require.NotPanics(t, func() {
+		sut.WithLabelValues("a0", "a1").Observe(0.1)
+	})
+
+	require.Panics(t, func() {
+		NewTTLNativeHistogramVecWithReconciliation(prometheus.HistogramOpts{
+			Namespace: "ns",
+			Name:      "native_metric_name_missing_factor",
+		}, labels, 10*time.Millisecond, 10*time.Millisecond)
+	})
 }
 
 func TestPrometheusHistogramVecWithoutTtl(t *testing.T) {
@@ -175,7 +205,7 @@ func TestPrometheusHistogramVecWithoutTtl(t *testing.T) {
 
 	// then
 	assert.Equal(t, time.Duration(0), sut.ttl)
-	require.Len(t, sut.cache, 0)
+	require.Equal(t, 0, sut.cache.len())
 
 	requireDataSeries(t, registry, 2)
 
@@ -183,10 +213,212 @@ func TestPrometheusHistogramVecWithoutTtl(t *testing.T) {
 	time.Sleep(25 * time.Millisecond)
 
 	// then
-	require.Len(t, sut.cache, 0)
+	require.Equal(t, 0, sut.cache.len())
 	requireDataSeries(t, registry, 2)
 }
 
+func TestPrometheusCounterVecWithMaxSeries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	registry := prometheus.NewRegistry()
+
+	labels := []string{"flag", "family"}
+
+	sut := NewBoundedCounterVec(prometheus.CounterOpts{
+		Namespace: "ns",
+		Name:      "metric_name",
+	}, labels, 0, 2, 0)
+
+	registry.MustRegister(sut)
+
+	// when sending three distinct label sets against a maxSeries of 2
+	sut.WithLabelValues("a0", "a1").Inc()
+	sut.WithLabelValues("b0", "b1").Inc()
+	sut.WithLabelValues("c0", "c1").Inc()
+
+	// then the least recently used series ("a0", "a1") is evicted
+	requireDataSeries(t, registry, 2)
+	require.Equal(t, 2, sut.cache.len())
+}
+
+func TestPrometheusCounterVecWithLFUPolicy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	registry := prometheus.NewRegistry()
+
+	labels := []string{"flag", "family"}
+
+	sut := NewLFUCounterVec(prometheus.CounterOpts{
+		Namespace: "ns",
+		Name:      "metric_name",
+	}, labels, 2)
+
+	registry.MustRegister(sut)
+
+	// when "a0" and "b0" are both touched twice, filling the maxSeries of 2,
+	// then a never-before-seen "c0" arrives once
+	sut.WithLabelValues("a0", "a1").Inc()
+	sut.WithLabelValues("a0", "a1").Inc()
+	sut.WithLabelValues("b0", "b1").Inc()
+	sut.WithLabelValues("b0", "b1").Inc()
+	sut.WithLabelValues("c0", "c1").Inc()
+
+	// then "c0" is evicted: being the least frequently used series it
+	// doesn't displace either established series
+	requireDataSeries(t, registry, 2)
+	require.Equal(t, 2, sut.cache.len())
+}
+
+func TestCounterVecAddWithExemplar(t *testing.T) {
+	t.Parallel()
+
+	sut := NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ns",
+		Name:      "exemplar_metric_name",
+	}, []string{"flag"}, 0)
+
+	require.NotPanics(t, func() {
+		sut.AddWithExemplar(1, prometheus.Labels{"traceID": "abc123"}, "a0")
+	})
+
+	oversized := make([]byte, 129)
+	require.Panics(t, func() {
+		sut.AddWithExemplar(1, prometheus.Labels{"traceID": string(oversized)}, "a0")
+	})
+}
+
+func TestPrometheusGaugeVecWithTtl(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+
+	labels := []string{"flag", "family"}
+
+	sut := NewTTLGaugeVecWithReconciliation(prometheus.GaugeOpts{
+		Namespace: "ns",
+		Name:      "metric_name",
+	}, labels, 10*time.Millisecond, 10*time.Millisecond)
+	defer sut.gcTicker.Stop()
+
+	registry.MustRegister(sut)
+
+	sut.WithLabelValues("a0", "a1").Set(1)
+	requireDataSeries(t, registry, 1)
+	require.Equal(t, 1, sut.cache.len())
+
+	time.Sleep(25 * time.Millisecond)
+
+	require.Equal(t, 0, sut.cache.len())
+}
+
+func TestCounterVecGather(t *testing.T) {
+	t.Parallel()
+
+	sut := NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ns",
+		Name:      "gather_metric_name",
+	}, []string{"flag"}, 0)
+
+	sut.WithLabelValues("a0").Inc()
+	sut.WithLabelValues("b0").Add(2)
+
+	families, err := sut.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Len(t, families[0].Metric, 2)
+
+	// Gather is reusable across repeated calls, not a one-shot.
+	sut.WithLabelValues("c0").Inc()
+	families, err = sut.Gather()
+	require.NoError(t, err)
+	require.Len(t, families[0].Metric, 3)
+}
+
+func TestPushSinkFlushesLiveSeriesAndDeletesWhenEmpty(t *testing.T) {
+	t.Parallel()
+
+	var mu lock.Mutex
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sut := NewTTLCounterVecWithReconciliation(prometheus.CounterOpts{
+		Namespace: "ns",
+		Name:      "push_metric_name",
+	}, []string{"flag"}, 10*time.Millisecond, 10*time.Millisecond)
+	defer sut.gcTicker.Stop()
+
+	sut.WithLabelValues("a0").Inc()
+
+	sink := sut.AttachPushSink(push.New(server.URL, "test-job"), 5*time.Millisecond)
+	defer sink.Stop()
+
+	// while "a0" is still live, every flush pushes the current series
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(methods) > 0
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, http.MethodPut, methods[0])
+	mu.Unlock()
+
+	// once "a0" expires, the next flush deletes the group instead
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(methods) > 0 && methods[len(methods)-1] == http.MethodDelete
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestPushSinkFlushesLiveSeriesWithoutTtl(t *testing.T) {
+	t.Parallel()
+
+	var mu lock.Mutex
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// a vec with both ttl and maxSeries disabled has a nil cache, so
+	// liveSeries must not derive its count from it.
+	sut := NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ns",
+		Name:      "push_metric_name_no_ttl",
+	}, []string{"flag"}, 0)
+
+	sut.WithLabelValues("a0").Inc()
+
+	sink := sut.AttachPushSink(push.New(server.URL, "test-job-no-ttl"), 5*time.Millisecond)
+	defer sink.Stop()
+
+	// the series never expires, so every flush should keep pushing rather
+	// than ever falling back to deleting the group.
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(methods) >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, m := range methods {
+		assert.Equal(t, http.MethodPut, m)
+	}
+}
+
 func requireDataSeries(t *testing.T, registry *prometheus.Registry, series int) {
 	metricFamilies, err := registry.Gather()
 	require.NoError(t, err)
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PushSink periodically flushes a TTL/LRU-wrapped metric vec's currently
+// live series to a Prometheus Pushgateway. This is for short-lived Cilium
+// jobs (the CNI installer, migration tools, one-shot policy validators)
+// whose metrics would otherwise disappear before the next scrape arrives.
+//
+// Because a Pushgateway push replaces the job's entire metric group with
+// whatever the wrapped vec currently gathers, a series the eviction policy
+// has dropped is simply absent from the next flush - no separate per-series
+// delete is needed for that. Once the wrapped vec has no live series left,
+// PushSink removes the now-empty group from the gateway entirely instead of
+// pushing nothing.
+type PushSink struct {
+	pusher     *push.Pusher
+	liveSeries func() int
+	ticker     *time.Ticker
+	done       chan struct{}
+}
+
+func newPushSink(pusher *push.Pusher, gatherer prometheus.Gatherer, interval time.Duration, liveSeries func() int) *PushSink {
+	s := &PushSink{
+		pusher:     pusher.Gatherer(gatherer),
+		liveSeries: liveSeries,
+		ticker:     time.NewTicker(interval),
+		done:       make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// run flushes the wrapped collector's current series to the gateway every
+// interval, using a background context since a Pushgateway write isn't tied
+// to any single caller's request lifecycle.
+func (s *PushSink) run() {
+	for {
+		select {
+		case <-s.ticker.C:
+			s.flush(context.Background())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// flush pushes the collector's currently live series, or, once no live
+// series remain, removes the job's metric group from the gateway entirely.
+func (s *PushSink) flush(ctx context.Context) error {
+	if s.liveSeries() == 0 {
+		return s.pusher.DeleteContext(ctx)
+	}
+	return s.pusher.PushContext(ctx)
+}
+
+// Stop halts the periodic flush. It does not delete whatever was last
+// pushed to the gateway; callers that want the group removed on shutdown
+// should push the wrapper down to zero series (e.g. let its TTL expire)
+// before calling Stop, or issue their own pusher.DeleteContext.
+func (s *PushSink) Stop() {
+	s.ticker.Stop()
+	close(s.done)
+}
+
+// AttachPushSink registers v's series with pusher and starts periodically
+// flushing its currently live set to the Pushgateway every interval.
+func (v *CounterVec) AttachPushSink(pusher *push.Pusher, interval time.Duration) *PushSink {
+	return newPushSink(pusher, v, interval, v.liveSeries)
+}
+
+// AttachPushSink is the HistogramVec counterpart of CounterVec.AttachPushSink.
+func (v *HistogramVec) AttachPushSink(pusher *push.Pusher, interval time.Duration) *PushSink {
+	return newPushSink(pusher, v, interval, v.liveSeries)
+}
+
+// AttachPushSink is the GaugeVec counterpart of CounterVec.AttachPushSink.
+func (v *GaugeVec) AttachPushSink(pusher *push.Pusher, interval time.Duration) *PushSink {
+	return newPushSink(pusher, v, interval, v.liveSeries)
+}
+
+// liveSeries reports v's currently live series count. It defers to v.cache,
+// the cheap O(1) path, whenever one exists; v.cache is only nil for a vec
+// built with both ttl and maxSeries disabled (an explicitly supported "no
+// eviction machinery needed" configuration, e.g. for a short-lived one-shot
+// job), in which case it falls back to counting straight off Gather's
+// output. Counting through a nil cache would report zero series on every
+// flush for such a vec, so PushSink would delete the job's metric group
+// instead of ever pushing it.
+func (v *CounterVec) liveSeries() int {
+	if v.cache != nil {
+		return v.cache.len()
+	}
+	return gatherSeriesCount(v)
+}
+
+// liveSeries is the HistogramVec counterpart of CounterVec.liveSeries.
+func (v *HistogramVec) liveSeries() int {
+	if v.cache != nil {
+		return v.cache.len()
+	}
+	return gatherSeriesCount(v)
+}
+
+// liveSeries is the GaugeVec counterpart of CounterVec.liveSeries.
+func (v *GaugeVec) liveSeries() int {
+	if v.cache != nil {
+		return v.cache.len()
+	}
+	return gatherSeriesCount(v)
+}
+
+// gatherSeriesCount counts a cacheless vec's live series off its own Gather
+// output, the only signal available once there's no cache to ask.
+func gatherSeriesCount(g prometheus.Gatherer) int {
+	families, err := g.Gather()
+	if err != nil || len(families) == 0 {
+		return 0
+	}
+	return len(families[0].Metric)
+}
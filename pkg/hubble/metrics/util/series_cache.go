@@ -0,0 +1,120 @@
+package util
+
+import (
+	"time"
+
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ttlMetricEvictionsTotal counts series a seriesCache's EvictionPolicy has
+// dropped, broken down by the policy that made the call and why, so
+// operators can alarm on cardinality explosions ("why is lfu evicting
+// max_series so often?") independently of which wrapped metric it happened
+// on (seriesEvictedTotal covers that axis).
+var ttlMetricEvictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cilium",
+	Subsystem: "ttl_metric",
+	Name:      "evictions_total",
+	Help:      "Number of metric series evicted by a TTL/LRU wrapper's eviction policy",
+}, []string{"policy", "reason"})
+
+func init() {
+	prometheus.MustRegister(ttlMetricEvictionsTotal)
+}
+
+// seriesCache is the bookkeeping shared by CounterVec, HistogramVec and
+// GaugeVec: it tracks which label sets are currently live and delegates the
+// decision of which to evict, and when, to a pluggable EvictionPolicy.
+type seriesCache struct {
+	metricName string
+	policy     EvictionPolicy
+	onEvict    func(lvs []string)
+
+	mutex  lock.Mutex
+	labels map[uint64][]string
+}
+
+func newSeriesCache(metricName string, policy EvictionPolicy, onEvict func(lvs []string)) *seriesCache {
+	return &seriesCache{
+		metricName: metricName,
+		policy:     policy,
+		onEvict:    onEvict,
+		labels:     make(map[uint64][]string),
+	}
+}
+
+// lock and unlock are nil-safe so Gather() can serialize against eviction
+// even on a vec that was constructed without a cache (ttl and maxSeries both
+// disabled), without every caller needing its own nil check.
+func (c *seriesCache) lock() {
+	if c != nil {
+		c.mutex.Lock()
+	}
+}
+
+func (c *seriesCache) unlock() {
+	if c != nil {
+		c.mutex.Unlock()
+	}
+}
+
+// touch records lvs as freshly accessed, registering it with the
+// EvictionPolicy if this is the first time it's been seen, and evicts
+// whatever series the policy names in response.
+func (c *seriesCache) touch(lvs []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := hashLabelValues(lvs)
+	now := time.Now()
+
+	if _, ok := c.labels[key]; ok {
+		c.policy.Touch(key, now)
+		return
+	}
+
+	c.labels[key] = lvs
+	seriesActive.WithLabelValues(c.metricName).Set(float64(len(c.labels)))
+
+	for _, evictKey := range c.policy.Add(key, now) {
+		c.evictLocked(evictKey, "max_series")
+	}
+}
+
+// sweep asks the EvictionPolicy which series have expired and evicts them.
+// It is invoked periodically by a vec's gc goroutine.
+func (c *seriesCache) sweep() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, key := range c.policy.Sweep(time.Now()) {
+		c.evictLocked(key, "ttl")
+	}
+}
+
+// evictLocked removes key's series from the cache and the underlying
+// metric vec, and accounts for the eviction. Callers must hold c.mutex.
+func (c *seriesCache) evictLocked(key uint64, reason string) {
+	lvs, ok := c.labels[key]
+	if !ok {
+		return
+	}
+	delete(c.labels, key)
+	c.policy.Remove(key)
+	c.onEvict(lvs)
+	seriesEvictedTotal.WithLabelValues(c.metricName, reason).Inc()
+	ttlMetricEvictionsTotal.WithLabelValues(c.policy.Name(), reason).Inc()
+	seriesActive.WithLabelValues(c.metricName).Set(float64(len(c.labels)))
+}
+
+// len reports the number of series currently tracked. It is nil-safe: a vec
+// with no cache (ttl and maxSeries both disabled) tracks zero series.
+func (c *seriesCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.labels)
+}